@@ -3,6 +3,7 @@ module github.com/crazy-choose/go
 go 1.24.4
 
 require (
+	github.com/alicebob/miniredis/v2 v2.37.0
 	github.com/andreburgaud/crypt2go v1.5.0
 	github.com/bytedance/sonic v1.11.6
 	github.com/crazy-choose/helper v1.9.0
@@ -49,6 +50,7 @@ require (
 	github.com/rogpeppe/go-internal v1.14.1 // indirect
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/ugorji/go/codec v1.2.12 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
 	golang.org/x/arch v0.8.0 // indirect
 	golang.org/x/net v0.25.0 // indirect
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultErrorBodyLimit = 1 << 10
+
+// StatusRange 闭区间状态码范围
+type StatusRange struct {
+	Min, Max int
+}
+
+// Status2xx 默认接受的状态码范围
+var Status2xx = StatusRange{Min: 200, Max: 299}
+
+// Status 单个状态码
+func Status(code int) StatusRange {
+	return StatusRange{Min: code, Max: code}
+}
+
+func (r StatusRange) contains(code int) bool { return code >= r.Min && code <= r.Max }
+
+func accepted(ranges []StatusRange, code int) bool {
+	for _, r := range ranges {
+		if r.contains(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPError 状态码不在接受范围内的响应
+type HTTPError struct {
+	Method     string
+	URL        string // 不含查询参数，避免签名等敏感信息进入日志
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte // 响应体，最多保留 DefaultErrorBodyLimit 字节
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+	if len(e.Body) > 0 {
+		msg += ": " + string(e.Body)
+	}
+	return msg
+}
+
+// RetryAfter 服务端通过 Retry-After 头建议的等待时间，供 policy.LimiterRetry 代替退避时间
+func (e *HTTPError) RetryAfter() time.Duration {
+	return parseRetryAfter(e.Header.Get("Retry-After"), time.Now())
+}
+
+// parseRetryAfter 解析秒数或 HTTP 日期格式的 Retry-After，无法解析时返回 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+	return max(at.Sub(now), 0)
+}
+
+// AsHTTPError 提取错误链中的 HTTPError
+func AsHTTPError(err error) (*HTTPError, bool) {
+	var httpErr *HTTPError
+	ok := errors.As(err, &httpErr)
+	return httpErr, ok
+}
+
+func newHTTPError(req *http.Request, resp *http.Response, body []byte) *HTTPError {
+	u := *req.URL
+	u.RawQuery, u.User = "", nil
+	if len(body) > DefaultErrorBodyLimit {
+		body = body[:DefaultErrorBodyLimit]
+	}
+	return &HTTPError{
+		Method:     req.Method,
+		URL:        u.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crazy-choose/go/policy"
+)
+
+type brokerError struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func TestHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/order":
+			w.Header().Set("X-Request-Id", "req-1")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"51008","msg":"insufficient balance"}`))
+		case "/ticker":
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/large":
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(strings.Repeat("x", 2*DefaultErrorBodyLimit)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+	client := NewHttpClient(srv.URL, time.Second)
+	ctx := context.Background()
+
+	var reject brokerError
+	err := client.Post(ctx, "/order").Query("sign", "secret").JSON(map[string]string{"sz": "1"}).ErrorInto(&reject).Decode(nil)
+	httpErr, ok := AsHTTPError(err)
+	if !ok {
+		t.Fatalf("expected HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusBadRequest || httpErr.Method != http.MethodPost || httpErr.URL != srv.URL+"/order" ||
+		httpErr.Header.Get("X-Request-Id") != "req-1" || !strings.Contains(err.Error(), "insufficient balance") {
+		t.Fatalf("unexpected error %+v", httpErr)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Fatalf("query leaked into error: %v", err)
+	}
+	if reject.Code != "51008" {
+		t.Fatalf("error body not decoded: %+v", reject)
+	}
+
+	err = client.Get(ctx, "/ticker").Decode(nil)
+	if after, ok := policy.RetryAfterOf(err); !ok || after != 2*time.Second {
+		t.Fatalf("retry after %v (%v) from %v", after, ok, err)
+	}
+
+	err = client.Get(ctx, "/large").Decode(nil)
+	if httpErr, _ := AsHTTPError(err); httpErr == nil || len(httpErr.Body) != DefaultErrorBodyLimit {
+		t.Fatalf("body snippet not truncated: %v", err)
+	}
+
+	if err := client.Delete(ctx, "/missing").Accept(Status2xx, Status(http.StatusNotFound)).Decode(nil); err != nil {
+		t.Fatalf("accepted status returned error: %v", err)
+	}
+	client.SetAcceptStatus(StatusRange{Min: 200, Max: 499})
+	if err := client.Get(ctx, "/missing").Decode(nil); err != nil {
+		t.Fatalf("client accept range ignored: %v", err)
+	}
+}
+
+func TestSendPOSTFormIgnoreErr(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"1","msg":"bad param"}`))
+	}))
+	client := NewHttpClient(srv.URL, time.Second)
+	ctx := context.Background()
+
+	var reject brokerError
+	if err := client.SendPOSTFormIgnoreErr(ctx, "/order", []byte("a=1"), &reject); err != nil || reject.Msg != "bad param" {
+		t.Fatalf("400 body not decoded: %+v, %v", reject, err)
+	}
+	if err := client.SendPOSTFormIgnoreErr(ctx, "/down", nil, &reject); err == nil {
+		t.Fatal("expected error for 500")
+	}
+
+	// transport errors are returned instead of dereferencing a nil response
+	srv.Close()
+	err := client.SendPOSTFormIgnoreErr(ctx, "/order", nil, &reject)
+	if _, ok := AsHTTPError(err); err == nil || ok || errors.Is(err, context.Canceled) {
+		t.Fatalf("expected transport error, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		" 3 ":                           3 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 00:00:05 GMT": 5 * time.Second,
+		"Sun, 31 Dec 2023 23:59:00 GMT": 0,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestSendAcceptsOnly200ByDefault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	client := NewHttpClient(srv.URL, time.Second)
+	ctx := context.Background()
+
+	if _, ok := AsHTTPError(client.SendGetJson(ctx, "/orders", nil, nil)); !ok {
+		t.Fatal("Send* accepted 202 without SetAcceptStatus")
+	}
+	if err := client.Get(ctx, "/orders").Decode(nil); err != nil {
+		t.Fatalf("request builder rejected 202: %v", err)
+	}
+	client.SetAcceptStatus(Status2xx)
+	if err := client.SendGetJson(ctx, "/orders", nil, nil); err != nil {
+		t.Fatalf("Send* ignored SetAcceptStatus: %v", err)
+	}
+}
@@ -1,12 +1,10 @@
 package http
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/crazy-choose/go/log"
@@ -17,6 +15,13 @@ var impl *IHttpClient
 type IHttpClient struct {
 	apiHost string
 	client  http.Client
+	breaker Breaker
+	accept  []StatusRange
+}
+
+// Breaker 熔断器，policy.Breaker 实现了该接口
+type Breaker interface {
+	Allow() (done func(err error), err error)
 }
 
 func NewHttpClient(host string, timeout time.Duration) *IHttpClient {
@@ -32,157 +37,77 @@ func NewHttpClient(host string, timeout time.Duration) *IHttpClient {
 	return impl
 }
 
-func (impl *IHttpClient) SendGetJson(ctx context.Context, url string, body []byte, internalClientRes interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, impl.apiHost+url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := impl.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
-	}
-	if internalClientRes == nil {
-		return nil
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+// SetBreaker 设置熔断器，传输错误、429 与 5xx 响应计为失败，熔断期间请求直接返回熔断错误
+func (impl *IHttpClient) SetBreaker(b Breaker) {
+	impl.breaker = b
+}
+
+// SetAcceptStatus 设置默认接受的状态码范围，其余状态码返回 *HTTPError
+// 未设置时链式请求接受 Status2xx，Send* 方法只接受 200
+func (impl *IHttpClient) SetAcceptStatus(ranges ...StatusRange) {
+	impl.accept = ranges
+}
+
+func (impl *IHttpClient) acceptStatus() []StatusRange {
+	if len(impl.accept) == 0 {
+		return []StatusRange{Status2xx}
 	}
-	err = json.Unmarshal(data, internalClientRes)
-	return err
+	return impl.accept
 }
 
-func (impl *IHttpClient) SendPOSTJson(ctx context.Context, url string, body []byte, internalClientRes interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, impl.apiHost+url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
+func (impl *IHttpClient) do(req *http.Request) (*http.Response, error) {
+	if impl.breaker == nil {
+		return impl.client.Do(req)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := impl.client.Do(req)
+
+	done, err := impl.breaker.Allow()
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
+		return nil, err
 	}
-	if internalClientRes == nil {
-		return nil
+	resp, err := impl.client.Do(req)
+	if err == nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError) {
+		done(errors.New(resp.Status))
+	} else {
+		done(err)
 	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	return resp, err
+}
+
+// legacy 创建 Send* 方法使用的请求，未调用 SetAcceptStatus 时与原行为一致，只接受 200
+func (impl *IHttpClient) legacy(ctx context.Context, method, url string) *Request {
+	req := impl.NewRequest(ctx, method, url)
+	if len(impl.accept) == 0 {
+		req.Accept(Status(http.StatusOK))
 	}
-	//return xml.Unmarshal(data, internalClientRes)
-	return json.Unmarshal(data, internalClientRes)
+	return req
+}
+
+func (impl *IHttpClient) SendGetJson(ctx context.Context, url string, body []byte, internalClientRes interface{}) error {
+	return impl.legacy(ctx, http.MethodGet, url).Body(body, ContentTypeJSON).Decode(internalClientRes)
+}
+
+func (impl *IHttpClient) SendPOSTJson(ctx context.Context, url string, body []byte, internalClientRes interface{}) error {
+	return impl.legacy(ctx, http.MethodPost, url).Body(body, ContentTypeJSON).Decode(internalClientRes)
 }
 
 func (impl *IHttpClient) SendGETForm(ctx context.Context, url, params string, internalClientRes interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, impl.apiHost+url+"?"+params, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	//	log.Info("SendGETForm: url:%s, req:%s", url, req)
-	resp, err := impl.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
-	}
-	if internalClientRes == nil {
-		return nil
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(data, internalClientRes)
+	return impl.legacy(ctx, http.MethodGet, url).RawQuery(params).Header("Content-Type", ContentTypeForm).Decode(internalClientRes)
 }
 
 func (impl *IHttpClient) SendPOSTForm(ctx context.Context, url string, body []byte, internalClientRes interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, impl.apiHost+url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF8")
-	resp, err := impl.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
-	}
-	if internalClientRes == nil {
-		return nil
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(data, internalClientRes)
+	return impl.legacy(ctx, http.MethodPost, url).Body(body, ContentTypeForm+"; charset=UTF8").Decode(internalClientRes)
 }
 
 func (impl *IHttpClient) SendGetHeader(ctx context.Context, url, headerKey, headerVal string, internalClientRes interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, impl.apiHost+url, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set(headerKey, headerVal)
-	resp, err := impl.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
-	}
-	if internalClientRes == nil {
-		return nil
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(data, internalClientRes)
+	return impl.legacy(ctx, http.MethodGet, url).Header(headerKey, headerVal).Decode(internalClientRes)
 }
 
-func (impl *IHttpClient) SendPOSTFormIgnoreErr(ctx context.Context, url string, body []byte, internalClientRes interface{}) (err error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, impl.apiHost+url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := impl.client.Do(req)
-	if err != nil {
-		log.Error("impl.client.Do error:", err)
-		if err.Error() == "400 Bad Request" {
-			err = nil
-		} else {
-			return
-		}
-	}
-
-	defer resp.Body.Close()
-	if internalClientRes == nil {
-		return
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Error("ioutil.ReadAll error:", err)
-		return
-	}
-	err = json.Unmarshal(data, internalClientRes)
+// SendPOSTFormIgnoreErr 与 SendPOSTForm 相同，但 400 响应视为正常，其响应体按 internalClientRes 解码
+func (impl *IHttpClient) SendPOSTFormIgnoreErr(ctx context.Context, url string, body []byte, internalClientRes interface{}) error {
+	req := impl.legacy(ctx, http.MethodPost, url)
+	err := req.Accept(append(slices.Clone(req.accept), Status(http.StatusBadRequest))...).Body(body, ContentTypeForm).Decode(internalClientRes)
 	if err != nil {
-		log.Error("json.Unmarshal error:", err)
+		log.Error("SendPOSTFormIgnoreErr %s error: %v", url, err)
 	}
-	return
+	return err
 }
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crazy-choose/go/policy"
+)
+
+func TestBreakerTripsOnServerErrors(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := NewHttpClient(srv.URL, time.Second)
+	client.SetBreaker(policy.NewBreaker(policy.BreakerOption{Name: "broker", ConsecutiveFailures: 2}))
+
+	for i := 0; i < 2; i++ {
+		if err := client.SendGetJson(context.Background(), "/orders", nil, nil); err == nil {
+			t.Fatal("expected error for 503")
+		}
+	}
+	if err := client.SendGetJson(context.Background(), "/orders", nil, nil); !errors.Is(err, policy.ErrBreakerOpen) {
+		t.Fatalf("expected open breaker, got %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("server hit %d times, want 2", hits.Load())
+	}
+}
@@ -0,0 +1,277 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// Decoder 响应体解码函数，json.Unmarshal、xml.Unmarshal 均可直接使用
+type Decoder func(data []byte, v any) error
+
+// File multipart 请求中的文件
+type File struct {
+	Field  string    // 表单字段名
+	Name   string    // 文件名
+	Reader io.Reader // 文件内容
+}
+
+// Request 链式请求构建器，由 IHttpClient.NewRequest 或 Get/Post/Put/Patch/Delete 创建
+// 构建过程中的错误延迟到 Do/Decode/Bytes 时返回
+type Request struct {
+	client   *IHttpClient
+	ctx      context.Context
+	method   string
+	path     string
+	rawQuery string
+	query    url.Values
+	header   http.Header
+	body     []byte
+	decoder  Decoder
+	accept   []StatusRange
+	errorV   any
+	err      error
+}
+
+// NewRequest 创建请求，path 可包含 {name} 形式的路径参数，由 Param 替换
+func (impl *IHttpClient) NewRequest(ctx context.Context, method, path string) *Request {
+	return &Request{
+		client:  impl,
+		ctx:     ctx,
+		method:  method,
+		path:    path,
+		query:   url.Values{},
+		header:  http.Header{},
+		decoder: json.Unmarshal,
+		accept:  impl.acceptStatus(),
+	}
+}
+
+func (impl *IHttpClient) Get(ctx context.Context, path string) *Request {
+	return impl.NewRequest(ctx, http.MethodGet, path)
+}
+
+func (impl *IHttpClient) Post(ctx context.Context, path string) *Request {
+	return impl.NewRequest(ctx, http.MethodPost, path)
+}
+
+func (impl *IHttpClient) Put(ctx context.Context, path string) *Request {
+	return impl.NewRequest(ctx, http.MethodPut, path)
+}
+
+func (impl *IHttpClient) Patch(ctx context.Context, path string) *Request {
+	return impl.NewRequest(ctx, http.MethodPatch, path)
+}
+
+func (impl *IHttpClient) Delete(ctx context.Context, path string) *Request {
+	return impl.NewRequest(ctx, http.MethodDelete, path)
+}
+
+// Param 替换路径中的 {name}，value 会做路径转义
+func (r *Request) Param(name, value string) *Request {
+	placeholder := "{" + name + "}"
+	if !strings.Contains(r.path, placeholder) {
+		r.fail(fmt.Errorf("path %q has no parameter %q", r.path, name))
+		return r
+	}
+	r.path = strings.ReplaceAll(r.path, placeholder, url.PathEscape(value))
+	return r
+}
+
+// Query 追加查询参数
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Queries 追加多个查询参数
+func (r *Request) Queries(values url.Values) *Request {
+	for key, vs := range values {
+		for _, v := range vs {
+			r.query.Add(key, v)
+		}
+	}
+	return r
+}
+
+// RawQuery 追加已编码的查询串，用于需要保持参数顺序的签名场景
+func (r *Request) RawQuery(query string) *Request {
+	if query == "" {
+		return r
+	}
+	if r.rawQuery != "" {
+		r.rawQuery += "&"
+	}
+	r.rawQuery += query
+	return r
+}
+
+// Header 设置请求头，覆盖同名请求头
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Headers 设置多个请求头
+func (r *Request) Headers(headers map[string]string) *Request {
+	for key, value := range headers {
+		r.header.Set(key, value)
+	}
+	return r
+}
+
+// Body 使用原始请求体，contentType 为空时不设置 Content-Type
+func (r *Request) Body(data []byte, contentType string) *Request {
+	r.body = data
+	if contentType != "" {
+		r.header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+// JSON 以 JSON 编码 v 作为请求体
+func (r *Request) JSON(v any) *Request {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.fail(fmt.Errorf("encode json body: %w", err))
+		return r
+	}
+	return r.Body(data, ContentTypeJSON)
+}
+
+// Form 以 x-www-form-urlencoded 编码 values 作为请求体
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body([]byte(values.Encode()), ContentTypeForm)
+}
+
+// Multipart 以 multipart/form-data 编码字段与文件作为请求体
+func (r *Request) Multipart(fields map[string]string, files ...File) *Request {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for key, value := range fields {
+		if err := w.WriteField(key, value); err != nil {
+			r.fail(fmt.Errorf("encode multipart field %s: %w", key, err))
+			return r
+		}
+	}
+	for _, f := range files {
+		part, err := w.CreateFormFile(f.Field, f.Name)
+		if err == nil {
+			_, err = io.Copy(part, f.Reader)
+		}
+		if err != nil {
+			r.fail(fmt.Errorf("encode multipart file %s: %w", f.Name, err))
+			return r
+		}
+	}
+	if err := w.Close(); err != nil {
+		r.fail(fmt.Errorf("encode multipart body: %w", err))
+		return r
+	}
+	return r.Body(buf.Bytes(), w.FormDataContentType())
+}
+
+// DecodeWith 设置响应体解码函数，默认 json.Unmarshal
+func (r *Request) DecodeWith(decoder Decoder) *Request {
+	r.decoder = decoder
+	return r
+}
+
+// Accept 设置本次请求接受的状态码范围，默认使用客户端的设置
+func (r *Request) Accept(ranges ...StatusRange) *Request {
+	r.accept = ranges
+	return r
+}
+
+// ErrorInto 状态码不在接受范围内时将响应体解码到 v，用于读取接口的业务错误码
+// 返回的错误仍为 *HTTPError
+func (r *Request) ErrorInto(v any) *Request {
+	r.errorV = v
+	return r
+}
+
+func (r *Request) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// URL 请求的完整地址
+func (r *Request) URL() string {
+	u := r.client.apiHost + r.path
+	query := r.rawQuery
+	if encoded := r.query.Encode(); encoded != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += encoded
+	}
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+// Do 发送请求，调用方需关闭返回的响应体
+func (r *Request) Do() (*http.Response, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(r.ctx, r.method, r.URL(), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	return r.client.do(req)
+}
+
+// Bytes 发送请求并返回响应体，状态码不在接受范围内时返回 *HTTPError
+func (r *Request) Bytes() ([]byte, error) {
+	resp, err := r.Do()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if accepted(r.accept, resp.StatusCode) {
+		return data, nil
+	}
+
+	httpErr := newHTTPError(resp.Request, resp, data)
+	if r.errorV != nil && len(data) > 0 {
+		if err := r.decoder(data, r.errorV); err != nil {
+			return nil, fmt.Errorf("%w; decode error body: %w", httpErr, err)
+		}
+	}
+	return nil, httpErr
+}
+
+// Decode 发送请求并将响应体解码到 v，v 为 nil 或响应无内容时不解码
+func (r *Request) Decode(v any) error {
+	data, err := r.Bytes()
+	if err != nil || v == nil || len(data) == 0 {
+		return err
+	}
+	return r.decoder(data, v)
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type echo struct {
+	Method      string              `json:"method" xml:"method"`
+	Path        string              `json:"path" xml:"path"`
+	Query       string              `json:"query" xml:"query"`
+	ContentType string              `json:"content_type" xml:"content_type"`
+	Headers     map[string]string   `json:"headers" xml:"-"`
+	Body        string              `json:"body" xml:"body"`
+	Form        map[string][]string `json:"form" xml:"-"`
+	Files       map[string]string   `json:"files" xml:"-"`
+}
+
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := echo{
+			Method:      r.Method,
+			Path:        r.URL.EscapedPath(),
+			Query:       r.URL.RawQuery,
+			ContentType: r.Header.Get("Content-Type"),
+			Headers:     map[string]string{"X-Api-Key": r.Header.Get("X-Api-Key"), "X-Sign": r.Header.Get("X-Sign")},
+		}
+		if strings.HasPrefix(e.ContentType, "multipart/") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Error(err)
+			}
+			e.Form = r.MultipartForm.Value
+			e.Files = map[string]string{}
+			for field, headers := range r.MultipartForm.File {
+				f, _ := headers[0].Open()
+				data, _ := io.ReadAll(f)
+				e.Files[field] = headers[0].Filename + ":" + string(data)
+			}
+		} else {
+			data, _ := io.ReadAll(r.Body)
+			e.Body = string(data)
+		}
+		if r.URL.Query().Get("format") == "xml" {
+			_ = xml.NewEncoder(w).Encode(e)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(e)
+	}))
+}
+
+func TestRequestBuilder(t *testing.T) {
+	srv := echoServer(t)
+	defer srv.Close()
+	client := NewHttpClient(srv.URL, time.Second)
+	ctx := context.Background()
+
+	var got echo
+	err := client.Put(ctx, "/accounts/{account}/orders/{id}").
+		Param("account", "sub/1").
+		Param("id", "42").
+		Query("symbol", "BTC-USDT").
+		Queries(url.Values{"side": {"buy"}}).
+		Headers(map[string]string{"X-Api-Key": "key", "X-Sign": "sig"}).
+		JSON(map[string]any{"px": "3650.5"}).
+		Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := echo{
+		Method:      http.MethodPut,
+		Path:        "/accounts/sub%2F1/orders/42",
+		Query:       "side=buy&symbol=BTC-USDT",
+		ContentType: ContentTypeJSON,
+		Headers:     map[string]string{"X-Api-Key": "key", "X-Sign": "sig"},
+		Body:        `{"px":"3650.5"}`,
+	}
+	if got.Method != want.Method || got.Path != want.Path || got.Query != want.Query || got.ContentType != want.ContentType ||
+		got.Headers["X-Api-Key"] != "key" || got.Headers["X-Sign"] != "sig" || got.Body != want.Body {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	got = echo{}
+	if err := client.Delete(ctx, "/orders").Form(url.Values{"id": {"1", "2"}}).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodDelete || got.ContentType != ContentTypeForm || got.Body != "id=1&id=2" {
+		t.Fatalf("unexpected form request %+v", got)
+	}
+
+	got = echo{}
+	err = client.Post(ctx, "/upload").
+		Multipart(map[string]string{"desc": "kyc"}, File{Field: "doc", Name: "id.txt", Reader: strings.NewReader("content")}).
+		Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Form["desc"][0] != "kyc" || got.Files["doc"] != "id.txt:content" {
+		t.Fatalf("unexpected multipart request %+v", got)
+	}
+
+	got = echo{}
+	if err := client.Patch(ctx, "/orders").RawQuery("b=2&a=1").Query("format", "xml").DecodeWith(xml.Unmarshal).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodPatch || got.Query != "b=2&a=1&format=xml" {
+		t.Fatalf("unexpected xml response %+v", got)
+	}
+
+	if err := client.Get(ctx, "/orders/{id}").Param("order", "1").Decode(nil); err == nil {
+		t.Fatal("expected error for unknown path parameter")
+	}
+}
+
+func TestSendWrappers(t *testing.T) {
+	srv := echoServer(t)
+	defer srv.Close()
+	client := NewHttpClient(srv.URL, time.Second)
+	ctx := context.Background()
+
+	var got echo
+	if err := client.SendGETForm(ctx, "/ticker", "instId=BTC-USDT&sz=1", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodGet || got.Query != "instId=BTC-USDT&sz=1" || got.ContentType != ContentTypeForm {
+		t.Fatalf("unexpected GET form request %+v", got)
+	}
+
+	got = echo{}
+	if err := client.SendPOSTJson(ctx, "/order", []byte(`{"sz":"1"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodPost || got.ContentType != ContentTypeJSON || got.Body != `{"sz":"1"}` {
+		t.Fatalf("unexpected POST json request %+v", got)
+	}
+
+	got = echo{}
+	if err := client.SendGetHeader(ctx, "/balance", "X-Api-Key", "key", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Headers["X-Api-Key"] != "key" {
+		t.Fatalf("header not sent: %+v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackoffStrategy 退避策略，带抖动的策略可避免大量协程同时重试
+type BackoffStrategy int
+
+const (
+	BackoffExponential  BackoffStrategy = iota // InitialInterval * Multiplier^(n-1)，不超过 MaxInterval
+	BackoffFullJitter                          // [0, 指数退避) 内随机
+	BackoffEqualJitter                         // 指数退避的一半加 [0, 一半) 内随机
+	BackoffDecorrelated                        // [InitialInterval, 上次退避*3) 内随机，不超过 MaxInterval
+	BackoffConstant                            // 固定 InitialInterval
+	BackoffLinear                              // InitialInterval * n，不超过 MaxInterval
+)
+
+// delay 计算第 attempt 次失败后的退避时间，prev 为上一次退避时间
+func (p *Policy) delay(attempt int, prev time.Duration) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+
+	switch p.Backoff {
+	case BackoffFullJitter:
+		return jitter(0, p.exponential(attempt))
+	case BackoffEqualJitter:
+		half := p.exponential(attempt) / 2
+		return half + jitter(0, half)
+	case BackoffDecorrelated:
+		if prev < p.InitialInterval {
+			prev = p.InitialInterval
+		}
+		upper := prev * 3
+		if upper > p.MaxInterval || upper < prev {
+			upper = p.MaxInterval
+		}
+		return jitter(p.InitialInterval, upper)
+	case BackoffConstant:
+		return p.InitialInterval
+	case BackoffLinear:
+		if d := p.InitialInterval * time.Duration(attempt); d < p.MaxInterval && d/time.Duration(attempt) == p.InitialInterval {
+			return d
+		}
+		return p.MaxInterval
+	}
+	return p.exponential(attempt)
+}
+
+func (p *Policy) exponential(attempt int) time.Duration {
+	// 使用整数计算幂次，避免 float64 的 % 运算错误
+	backoff := float64(p.InitialInterval)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if backoff > float64(p.MaxInterval) {
+			return p.MaxInterval
+		}
+	}
+	return time.Duration(backoff)
+}
+
+// jitter 返回 [lower, upper) 内的随机时长，upper <= lower 时返回 lower
+func jitter(lower, upper time.Duration) time.Duration {
+	if upper <= lower {
+		return lower
+	}
+	return lower + time.Duration(rand.Int64N(int64(upper-lower)))
+}
+
+// RetryAfterError 携带服务端建议等待时间的错误，重试时以 After 代替计算出的退避时间
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error() + " (retry after " + e.After.String() + ")"
+}
+
+func (e *RetryAfterError) Unwrap() error { return e.Err }
+
+func (e *RetryAfterError) RetryAfter() time.Duration { return e.After }
+
+// WithRetryAfter 为 err 附加服务端建议的等待时间
+func WithRetryAfter(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryAfterError{Err: err, After: after}
+}
+
+// RetryAfterOf 提取错误链中实现 RetryAfter() time.Duration 的建议等待时间
+func RetryAfterOf(err error) (time.Duration, bool) {
+	var ra interface{ RetryAfter() time.Duration }
+	if !errors.As(err, &ra) {
+		return 0, false
+	}
+	if d := ra.RetryAfter(); d > 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+// ParseRetryAfter 解析 HTTP Retry-After 头，支持秒数与 HTTP 日期两种格式
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
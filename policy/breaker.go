@@ -0,0 +1,297 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBreakerOpen   = errors.New("circuit breaker is open")
+	ErrTooManyProbes = errors.New("circuit breaker probe limit reached")
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	StateClosed   BreakerState = iota // 正常放行
+	StateOpen                         // 熔断，直接拒绝
+	StateHalfOpen                     // 冷却结束，放行有限的探测请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+const (
+	DefaultBreakerWindow   = time.Minute
+	DefaultBreakerCoolDown = 30 * time.Second
+	breakerBuckets         = 10
+)
+
+// BreakerOption 熔断器配置，ConsecutiveFailures 与 FailureRate 任一满足即熔断
+type BreakerOption struct {
+	Name                string
+	ConsecutiveFailures int           // 连续失败次数阈值，0 不启用
+	FailureRate         float64       // 窗口内失败率阈值 (0, 1]，0 不启用
+	MinRequests         int           // 窗口内请求数达到后才按失败率判断
+	Window              time.Duration // 失败率统计窗口，默认 DefaultBreakerWindow
+	CoolDown            time.Duration // 熔断后进入半开前的冷却时间，默认 DefaultBreakerCoolDown
+	Probes              int           // 半开状态允许的并发探测数，全部成功后恢复，默认 1
+	IsFailure           func(error) bool
+	OnStateChange       func(name string, from, to BreakerState)
+	Clock               Clock
+}
+
+// BreakerCounts 当前统计窗口内的计数
+type BreakerCounts struct {
+	Requests            int
+	Failures            int
+	ConsecutiveFailures int
+}
+
+type breakerBucket struct {
+	start              time.Time
+	requests, failures int
+}
+
+// Breaker 熔断器，可单独使用，也可通过 Policy.Breaker 与 LimiterRetry 组合
+type Breaker struct {
+	opt         BreakerOption
+	mu          sync.Mutex
+	state       BreakerState
+	generation  uint64 // 状态切换时递增，忽略切换前发起的请求结果
+	expiry      time.Time
+	buckets     [breakerBuckets]breakerBucket
+	consecutive int
+	probing     int
+	probed      int
+}
+
+// NewBreaker 创建熔断器
+func NewBreaker(opt BreakerOption) *Breaker {
+	if opt.Window <= 0 {
+		opt.Window = DefaultBreakerWindow
+	}
+	if opt.CoolDown <= 0 {
+		opt.CoolDown = DefaultBreakerCoolDown
+	}
+	if opt.Probes <= 0 {
+		opt.Probes = 1
+	}
+	if opt.IsFailure == nil {
+		opt.IsFailure = func(err error) bool { return err != nil }
+	}
+	if opt.Clock == nil {
+		opt.Clock = RealClock{}
+	}
+	return &Breaker{opt: opt}
+}
+
+// Name 熔断器名称
+func (b *Breaker) Name() string { return b.opt.Name }
+
+// State 当前状态，冷却结束的熔断状态返回半开
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	state, notify := b.current(b.opt.Clock.Now())
+	b.mu.Unlock()
+	notify()
+	return state
+}
+
+// Counts 当前统计窗口内的计数
+func (b *Breaker) Counts() BreakerCounts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	counts := BreakerCounts{ConsecutiveFailures: b.consecutive}
+	since := b.opt.Clock.Now().Add(-b.opt.Window)
+	for _, bucket := range b.buckets {
+		if bucket.start.After(since) {
+			counts.Requests += bucket.requests
+			counts.Failures += bucket.failures
+		}
+	}
+	return counts
+}
+
+// Allow 申请执行一次请求，允许时返回的 done 需以请求结果调用一次
+// 熔断时返回 ErrBreakerOpen，半开状态探测数已满时返回 ErrTooManyProbes
+func (b *Breaker) Allow() (done func(err error), err error) {
+	generation, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.done(generation, b.opt.IsFailure(err)) })
+	}, nil
+}
+
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	state, notify := b.current(b.opt.Clock.Now())
+	var err error
+	switch state {
+	case StateOpen:
+		err = ErrBreakerOpen
+	case StateHalfOpen:
+		if b.probing >= b.opt.Probes-b.probed {
+			err = ErrTooManyProbes
+		} else {
+			b.probing++
+		}
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	notify()
+	return generation, err
+}
+
+// abort 放弃已申请但未发出的请求，不计入统计
+func (b *Breaker) abort(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation == b.generation && b.state == StateHalfOpen {
+		b.probing--
+	}
+}
+
+// Execute 在熔断器保护下执行 fn
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// BreakerDo 在熔断器保护下执行返回结果的函数
+func BreakerDo[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	done, err := b.Allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	result, err := fn(ctx)
+	done(err)
+	return result, err
+}
+
+func (b *Breaker) done(generation uint64, failed bool) {
+	b.mu.Lock()
+	now := b.opt.Clock.Now()
+	state, notify := b.current(now)
+	if generation != b.generation {
+		b.mu.Unlock()
+		notify()
+		return
+	}
+
+	var transition func()
+	switch state {
+	case StateClosed:
+		b.record(now, failed)
+		if failed && b.tripped(now) {
+			transition = b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.probing--
+		if failed {
+			transition = b.setState(StateOpen, now)
+		} else if b.probed++; b.probed >= b.opt.Probes {
+			transition = b.setState(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+
+	notify()
+	if transition != nil {
+		transition()
+	}
+}
+
+// current 冷却结束时切换到半开，返回的 notify 需在释放锁后调用，调用方需持有 mu
+func (b *Breaker) current(now time.Time) (BreakerState, func()) {
+	if b.state == StateOpen && !now.Before(b.expiry) {
+		return StateHalfOpen, b.setState(StateHalfOpen, now)
+	}
+	return b.state, func() {}
+}
+
+// setState 切换状态并重置计数，返回状态变化通知，调用方需持有 mu
+func (b *Breaker) setState(to BreakerState, now time.Time) func() {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.probing, b.probed = 0, 0
+	switch to {
+	case StateClosed:
+		b.buckets = [breakerBuckets]breakerBucket{}
+		b.consecutive = 0
+	case StateOpen:
+		b.expiry = now.Add(b.opt.CoolDown)
+	}
+
+	hook, name := b.opt.OnStateChange, b.opt.Name
+	if hook == nil || from == to {
+		return func() {}
+	}
+	return func() { hook(name, from, to) }
+}
+
+// record 记录一次请求结果，调用方需持有 mu
+func (b *Breaker) record(now time.Time, failed bool) {
+	width := int64(b.opt.Window / breakerBuckets)
+	if width <= 0 {
+		width = 1
+	}
+	slot := now.UnixNano() / width
+	start := time.Unix(0, slot*width)
+	bucket := &b.buckets[slot%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	bucket.requests++
+	if failed {
+		bucket.failures++
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+}
+
+// tripped 是否满足熔断条件，调用方需持有 mu
+func (b *Breaker) tripped(now time.Time) bool {
+	if b.opt.ConsecutiveFailures > 0 && b.consecutive >= b.opt.ConsecutiveFailures {
+		return true
+	}
+	if b.opt.FailureRate <= 0 {
+		return false
+	}
+
+	var requests, failures int
+	since := now.Add(-b.opt.Window)
+	for _, bucket := range b.buckets {
+		if bucket.start.After(since) {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+	return requests > 0 && requests >= b.opt.MinRequests &&
+		float64(failures)/float64(requests) >= b.opt.FailureRate
+}
@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestBreakerStates(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local))
+	var transitions []string
+	b := NewBreaker(BreakerOption{
+		Name:                "broker",
+		ConsecutiveFailures: 3,
+		CoolDown:            10 * time.Second,
+		Probes:              2,
+		Clock:               clock,
+		OnStateChange: func(name string, from, to BreakerState) {
+			transitions = append(transitions, name+":"+from.String()+"->"+to.String())
+		},
+	})
+	down := errors.New("down")
+
+	for i := 0; i < 3; i++ {
+		if err := b.Execute(func() error { return down }); !errors.Is(err, down) {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state %s after consecutive failures", b.State())
+	}
+	if err := b.Execute(func() error { t.Fatal("called while open"); return nil }); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("open breaker returned %v", err)
+	}
+
+	clock.Advance(10 * time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state %s after cool-down", b.State())
+	}
+	first, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.Allow(); !errors.Is(err, ErrTooManyProbes) {
+		t.Fatalf("third probe returned %v", err)
+	}
+	first(nil)
+	first(down) // repeated calls are ignored
+	if b.State() != StateHalfOpen {
+		t.Fatal("closed before all probes succeeded")
+	}
+	second(nil)
+	if b.State() != StateClosed {
+		t.Fatalf("state %s after successful probes", b.State())
+	}
+
+	// a failed probe re-opens the breaker
+	for i := 0; i < 3; i++ {
+		_ = b.Execute(func() error { return down })
+	}
+	clock.Advance(10 * time.Second)
+	_ = b.Execute(func() error { return down })
+	if b.State() != StateOpen {
+		t.Fatalf("state %s after failed probe", b.State())
+	}
+
+	want := []string{
+		"broker:closed->open", "broker:open->half-open", "broker:half-open->closed",
+		"broker:closed->open", "broker:open->half-open", "broker:half-open->open",
+	}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions %v, want %v", transitions, want)
+		}
+	}
+}
+
+func TestBreakerFailureRate(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local))
+	b := NewBreaker(BreakerOption{FailureRate: 0.5, MinRequests: 4, Window: 10 * time.Second, Clock: clock})
+	down := errors.New("down")
+
+	for _, err := range []error{down, nil, down} {
+		_ = b.Execute(func() error { return err })
+	}
+	if b.State() != StateClosed {
+		t.Fatal("tripped below MinRequests")
+	}
+
+	// failures age out of the window
+	clock.Advance(11 * time.Second)
+	if counts := b.Counts(); counts.Requests != 0 {
+		t.Fatalf("stale counts %+v", counts)
+	}
+	for _, err := range []error{nil, down, nil, down} {
+		_ = b.Execute(func() error { return err })
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state %s at 50%% failure rate", b.State())
+	}
+}
+
+func TestBreakerWithLimiterRetry(t *testing.T) {
+	b := NewBreaker(BreakerOption{ConsecutiveFailures: 2, CoolDown: time.Minute})
+	lr := NewLR(Policy{RateLimit: rate.Inf, MaxRetries: 5, InitialInterval: time.Millisecond, Breaker: b})
+	down := errors.New("down")
+
+	_, stats, err := DoStats(context.Background(), lr, func(context.Context) (int, error) { return 0, down })
+	if !errors.Is(err, ErrBreakerOpen) || !errors.Is(err, down) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if stats.Attempts != 2 {
+		t.Fatalf("attempts %d, want 2", stats.Attempts)
+	}
+
+	// canceled limiter waits are not counted against the dependency
+	b = NewBreaker(BreakerOption{ConsecutiveFailures: 1})
+	lr = NewLR(Policy{RateLimit: rate.Every(time.Hour), Burst: 1, Breaker: b})
+	lr.limiter.(*rate.Limiter).Allow()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = Do(ctx, lr, func(context.Context) (int, error) { return 1, nil }); err == nil {
+		t.Fatal("expected limiter error")
+	}
+	if b.State() != StateClosed || b.Counts().Requests != 0 {
+		t.Fatalf("limiter error recorded: %s %+v", b.State(), b.Counts())
+	}
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+const (
+	DefaultRetryRatio   = 0.1
+	DefaultMinRetries   = 10
+	DefaultBudgetWindow = 10 * time.Second
+	budgetBuckets       = 10
+)
+
+// RetryBudgetOption 重试预算配置，窗口内允许的重试数为 MinRetries + Ratio * 成功调用数
+type RetryBudgetOption struct {
+	Ratio      float64       // 重试数占窗口内成功调用数的比例，默认 DefaultRetryRatio
+	MinRetries int           // 窗口内保底的重试数，保证低流量时仍可重试，默认 DefaultMinRetries，< 0 表示不保底
+	Window     time.Duration // 统计窗口，默认 DefaultBudgetWindow
+	Clock      Clock
+}
+
+type budgetBucket struct {
+	start              time.Time
+	successes, retries int
+}
+
+// RetryBudget 重试预算，限制故障期间重试带来的额外负载
+// 通过 Policy.Budget 在同一 LimiterRetry 的全部调用间共享，也可由多个 LimiterRetry 共用
+type RetryBudget struct {
+	opt     RetryBudgetOption
+	mu      sync.Mutex
+	buckets [budgetBuckets]budgetBucket
+}
+
+// NewRetryBudget 创建重试预算
+func NewRetryBudget(opt RetryBudgetOption) *RetryBudget {
+	if opt.Ratio <= 0 {
+		opt.Ratio = DefaultRetryRatio
+	}
+	if opt.MinRetries == 0 {
+		opt.MinRetries = DefaultMinRetries
+	} else if opt.MinRetries < 0 {
+		opt.MinRetries = 0
+	}
+	if opt.Window <= 0 {
+		opt.Window = DefaultBudgetWindow
+	}
+	if opt.Clock == nil {
+		opt.Clock = RealClock{}
+	}
+	return &RetryBudget{opt: opt}
+}
+
+// Remaining 当前窗口内还可发起的重试数
+func (rb *RetryBudget) Remaining() int {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.remaining(rb.opt.Clock.Now())
+}
+
+// success 记录一次成功调用
+func (rb *RetryBudget) success() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.bucket(rb.opt.Clock.Now()).successes++
+}
+
+// withdraw 预算充足时扣减一次重试并返回 true
+func (rb *RetryBudget) withdraw() bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	now := rb.opt.Clock.Now()
+	if rb.remaining(now) <= 0 {
+		return false
+	}
+	rb.bucket(now).retries++
+	return true
+}
+
+// remaining 调用方需持有 mu
+func (rb *RetryBudget) remaining(now time.Time) int {
+	var successes, retries int
+	since := now.Add(-rb.opt.Window)
+	for _, bucket := range rb.buckets {
+		if bucket.start.After(since) {
+			successes += bucket.successes
+			retries += bucket.retries
+		}
+	}
+	return rb.opt.MinRetries + int(rb.opt.Ratio*float64(successes)) - retries
+}
+
+// bucket 返回 now 所在的统计桶，调用方需持有 mu
+func (rb *RetryBudget) bucket(now time.Time) *budgetBucket {
+	width := int64(rb.opt.Window / budgetBuckets)
+	if width <= 0 {
+		width = 1
+	}
+	slot := now.UnixNano() / width
+	start := time.Unix(0, slot*width)
+	bucket := &rb.buckets[slot%budgetBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = budgetBucket{start: start}
+	}
+	return bucket
+}
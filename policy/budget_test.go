@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRetryBudget(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 6, 3, 9, 30, 0, 0, time.UTC))
+	budget := NewRetryBudget(RetryBudgetOption{Ratio: 0.2, MinRetries: 2, Window: 10 * time.Second, Clock: clock})
+
+	if budget.Remaining() != 2 {
+		t.Fatalf("remaining %d, want the minimum 2", budget.Remaining())
+	}
+	for i := 0; i < 10; i++ {
+		budget.success()
+	}
+	if budget.Remaining() != 4 {
+		t.Fatalf("remaining %d after 10 successes, want 4", budget.Remaining())
+	}
+	for i := 0; i < 4; i++ {
+		if !budget.withdraw() {
+			t.Fatalf("retry %d rejected", i)
+		}
+	}
+	if budget.withdraw() {
+		t.Fatal("retry allowed beyond budget")
+	}
+
+	// successes and retries leave the window together
+	clock.Advance(11 * time.Second)
+	if budget.Remaining() != 2 {
+		t.Fatalf("remaining %d after the window, want 2", budget.Remaining())
+	}
+}
+
+func TestRetryBudgetSharedByLimiterRetry(t *testing.T) {
+	budget := NewRetryBudget(RetryBudgetOption{MinRetries: 3})
+	lr := NewLR(Policy{RateLimit: rate.Inf, MaxRetries: 5, InitialInterval: time.Millisecond, Budget: budget})
+	down := errors.New("exchange unavailable")
+
+	// the outage consumes the shared budget across calls instead of retrying each call 5 times
+	attempts := 0
+	for i := 0; i < 3; i++ {
+		_, stats, err := DoStats(context.Background(), lr, func(context.Context) (int, error) { return 0, down })
+		attempts += stats.Attempts
+		if !errors.Is(err, down) {
+			t.Fatalf("last error lost: %v", err)
+		}
+		if i > 0 && !errors.Is(err, ErrRetryBudgetExhausted) {
+			t.Fatalf("call %d: expected exhausted budget, got %v", i, err)
+		}
+	}
+	if attempts != 3+3 {
+		t.Fatalf("%d attempts, want 3 calls plus 3 budgeted retries", attempts)
+	}
+
+	if _, err := Do(context.Background(), lr, func(context.Context) (int, error) { return 1, nil }); err != nil {
+		t.Fatal(err)
+	}
+}
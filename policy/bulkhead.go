@@ -0,0 +1,207 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBulkheadFull    = errors.New("bulkhead queue is full")
+	ErrBulkheadTimeout = errors.New("bulkhead queue wait timeout")
+)
+
+const (
+	DefaultMaxConcurrent   = 10
+	DefaultAdaptiveLatency = 500 * time.Millisecond
+)
+
+// BulkheadOption 并发隔离配置
+type BulkheadOption struct {
+	MaxConcurrent int            // 最大并发数，默认 DefaultMaxConcurrent；开启自适应时为初始值
+	MaxQueue      int            // 等待队列长度，0 表示并发已满时立即拒绝
+	QueueTimeout  time.Duration  // 排队超时，0 表示只受 ctx 约束
+	Timeout       time.Duration  // 单次调用超时，通过 ctx 传入被调用函数，0 不限制
+	Adaptive      *AdaptiveLimit // 按观测延迟自适应调整并发数，nil 不启用
+}
+
+// AdaptiveLimit AIMD 自适应并发：调用成功且延迟不超过 Latency 时并发上限加性增长（每轮约 +1），
+// 失败或超过 Latency 时乘以 Decrease 乘性减小
+type AdaptiveLimit struct {
+	Min      int
+	Max      int
+	Latency  time.Duration // 视为慢调用的延迟阈值，默认 DefaultAdaptiveLatency
+	Decrease float64       // 默认 0.5
+}
+
+// Bulkhead 信号量式并发隔离，超出并发的调用进入有界队列按先后顺序等待
+type Bulkhead struct {
+	opt      BulkheadOption
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	waiters  []chan struct{}
+}
+
+// NewBulkhead 创建并发隔离器
+func NewBulkhead(opt BulkheadOption) *Bulkhead {
+	if opt.MaxConcurrent <= 0 {
+		opt.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if opt.MaxQueue < 0 {
+		opt.MaxQueue = 0
+	}
+	if a := opt.Adaptive; a != nil {
+		adaptive := *a
+		if adaptive.Min <= 0 {
+			adaptive.Min = 1
+		}
+		if adaptive.Max < adaptive.Min {
+			adaptive.Max = opt.MaxConcurrent
+		}
+		if adaptive.Latency <= 0 {
+			adaptive.Latency = DefaultAdaptiveLatency
+		}
+		if adaptive.Decrease <= 0 || adaptive.Decrease >= 1 {
+			adaptive.Decrease = 0.5
+		}
+		opt.Adaptive = &adaptive
+	}
+	return &Bulkhead{opt: opt, limit: float64(opt.MaxConcurrent)}
+}
+
+// Limit 当前并发上限
+func (b *Bulkhead) Limit() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.limit)
+}
+
+// Inflight 正在执行的调用数
+func (b *Bulkhead) Inflight() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.inflight
+}
+
+// Queued 排队中的调用数
+func (b *Bulkhead) Queued() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.waiters)
+}
+
+// Acquire 取得执行许可，返回的 release 需以调用结果调用一次
+// 队列已满返回 ErrBulkheadFull，排队超时返回 ErrBulkheadTimeout
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(err error), err error) {
+	b.mu.Lock()
+	if b.inflight < int(b.limit) && len(b.waiters) == 0 {
+		b.inflight++
+		b.mu.Unlock()
+		return b.releaser(), nil
+	}
+	if len(b.waiters) >= b.opt.MaxQueue {
+		b.mu.Unlock()
+		return nil, ErrBulkheadFull
+	}
+	ready := make(chan struct{}, 1)
+	b.waiters = append(b.waiters, ready)
+	b.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if b.opt.QueueTimeout > 0 {
+		timer := time.NewTimer(b.opt.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return b.releaser(), nil
+	case <-timeout:
+		err = ErrBulkheadTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	b.mu.Lock()
+	for i, w := range b.waiters {
+		if w == ready {
+			b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+			b.mu.Unlock()
+			return nil, err
+		}
+	}
+	b.mu.Unlock()
+
+	// 超时与放行同时发生，许可已转给本调用，交还给下一个等待者
+	<-ready
+	b.release(0, nil, false)
+	return nil, err
+}
+
+func (b *Bulkhead) releaser() func(err error) {
+	start := time.Now()
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.release(time.Since(start), err, true) })
+	}
+}
+
+// release 归还许可并唤醒等待者，observe 为 false 时不参与自适应调整
+func (b *Bulkhead) release(latency time.Duration, err error, observe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inflight--
+	if a := b.opt.Adaptive; a != nil && observe {
+		if err != nil || latency > a.Latency {
+			b.limit *= a.Decrease
+			if b.limit < float64(a.Min) {
+				b.limit = float64(a.Min)
+			}
+		} else {
+			b.limit += 1 / b.limit
+			if b.limit > float64(a.Max) {
+				b.limit = float64(a.Max)
+			}
+		}
+	}
+
+	for len(b.waiters) > 0 && b.inflight < int(b.limit) {
+		ready := b.waiters[0]
+		b.waiters[0] = nil
+		b.waiters = b.waiters[1:]
+		b.inflight++
+		ready <- struct{}{}
+	}
+}
+
+// Execute 在并发隔离下执行 fn，Timeout 通过 ctx 传入
+func (b *Bulkhead) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := BulkheadDo(ctx, b, func(ctx context.Context) (struct{}, error) { return struct{}{}, fn(ctx) })
+	return err
+}
+
+// BulkheadDo 在并发隔离下执行返回结果的函数
+func BulkheadDo[T any](ctx context.Context, b *Bulkhead, fn func(ctx context.Context) (T, error)) (T, error) {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	result, err := withTimeout(ctx, b.opt.Timeout, fn)
+	release(err)
+	return result, err
+}
+
+// withTimeout 按 Timeout 限制单次调用
+func withTimeout[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBulkheadQueue(t *testing.T) {
+	b := NewBulkhead(BulkheadOption{MaxConcurrent: 2, MaxQueue: 1, QueueTimeout: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	unblock := make(chan struct{})
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			results <- b.Execute(ctx, func(context.Context) error { <-unblock; return nil })
+		}()
+	}
+	waitFor(t, "two running and one queued", func() bool { return b.Inflight() == 2 && b.Queued() == 1 })
+
+	if err := b.Execute(ctx, func(context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("expected full queue, got %v", err)
+	}
+
+	close(unblock)
+	for i := 0; i < 3; i++ {
+		if err := <-results; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.Inflight() != 0 || b.Queued() != 0 {
+		t.Fatalf("leaked permits: inflight %d queued %d", b.Inflight(), b.Queued())
+	}
+
+	// the queued call gives up after QueueTimeout
+	hold, _ := b.Acquire(ctx)
+	hold2, _ := b.Acquire(ctx)
+	start := time.Now()
+	if _, err := b.Acquire(ctx); !errors.Is(err, ErrBulkheadTimeout) || time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("expected queue timeout, got %v after %v", err, time.Since(start))
+	}
+	hold(nil)
+	hold2(nil)
+	hold2(nil) // repeated release is ignored
+	if b.Inflight() != 0 {
+		t.Fatalf("inflight %d after release", b.Inflight())
+	}
+}
+
+func TestBulkheadCallTimeout(t *testing.T) {
+	b := NewBulkhead(BulkheadOption{MaxConcurrent: 1, Timeout: 10 * time.Millisecond})
+	err := b.Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected per-call timeout, got %v", err)
+	}
+}
+
+func TestBulkheadAdaptive(t *testing.T) {
+	b := NewBulkhead(BulkheadOption{MaxConcurrent: 8, Adaptive: &AdaptiveLimit{Min: 2, Max: 10, Latency: time.Second}})
+	ctx := context.Background()
+
+	_ = b.Execute(ctx, func(context.Context) error { return errors.New("gateway timeout") })
+	if b.Limit() != 4 {
+		t.Fatalf("limit %d after failure, want 4", b.Limit())
+	}
+	for i := 0; i < 2; i++ {
+		_ = b.Execute(ctx, func(context.Context) error { return errors.New("gateway timeout") })
+	}
+	if b.Limit() != 2 {
+		t.Fatalf("limit %d, want floor 2", b.Limit())
+	}
+
+	for i := 0; i < 100; i++ {
+		_ = b.Execute(ctx, func(context.Context) error { return nil })
+	}
+	if b.Limit() != 10 {
+		t.Fatalf("limit %d after successes, want ceiling 10", b.Limit())
+	}
+}
+
+func TestBulkheadAdaptiveDefaultLatency(t *testing.T) {
+	b := NewBulkhead(BulkheadOption{MaxConcurrent: 8, Adaptive: &AdaptiveLimit{Min: 1, Max: 16}})
+	for i := 0; i < 20; i++ {
+		_ = b.Execute(context.Background(), func(context.Context) error { return nil })
+	}
+	if b.Limit() < 8 {
+		t.Fatalf("limit %d after fast successes, want at least 8", b.Limit())
+	}
+}
+
+func TestBulkheadInPolicyPipeline(t *testing.T) {
+	bulkhead := NewBulkhead(BulkheadOption{MaxConcurrent: 1})
+	breaker := NewBreaker(BreakerOption{ConsecutiveFailures: 1})
+	lr := NewLR(Policy{RateLimit: rate.Inf, MaxRetries: 1, InitialInterval: time.Millisecond, Bulkhead: bulkhead, Breaker: breaker})
+
+	unblock := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := Do(context.Background(), lr, func(context.Context) (int, error) { <-unblock; return 1, nil })
+		done <- err
+	}()
+	waitFor(t, "slow call to start", func() bool { return bulkhead.Inflight() == 1 })
+
+	called := false
+	if _, err := Do(context.Background(), lr, func(context.Context) (int, error) { called = true; return 0, nil }); !errors.Is(err, ErrBulkheadFull) || called {
+		t.Fatalf("expected bulkhead rejection, got %v (called=%v)", err, called)
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != StateClosed {
+		t.Fatal("bulkhead rejection counted as a dependency failure")
+	}
+}
@@ -0,0 +1,240 @@
+package policy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crazy-choose/go/custom"
+	"github.com/crazy-choose/go/misc"
+)
+
+// Session 交易时段，Start/End 为距所在日零点的偏移
+// End <= Start 表示跨越午夜（如夜盘 21:00-02:30）
+// Night 为 true 时时段位于上一交易日晚间（国内期货夜盘归属下一交易日）
+type Session struct {
+	Name  string
+	Start time.Duration
+	End   time.Duration
+	Night bool
+}
+
+// CalendarEvent 日历展开后的单个事件
+type CalendarEvent struct {
+	Key       string
+	EventType int
+	EventTime time.Time     // 时段边界时间
+	Dur       time.Duration // 与 AddEvent 的 dur 含义一致
+	Session   string
+}
+
+// Calendar 交易日历，按交易日自动生成开仓/禁开/强平事件
+//   - EventOpenAllow: 每个时段开始后 OpenLag
+//   - EventCloseProhibit: 每个时段结束前 CloseLead
+//   - EventForceClose: 交易日最后一个时段结束前 ForceLead
+//
+// 交易日与时段按 Location 的当地时间计算，夏令时切换日的时段仍对应当地墙上时间；
+// Location 为 nil 时使用 time.Local
+type Calendar struct {
+	Key       string
+	Sessions  []Session
+	OpenLag   time.Duration
+	CloseLead time.Duration
+	ForceLead time.Duration
+	Location  *time.Location
+	holidays  custom.Set[string]
+}
+
+// NewCalendar 创建交易日历，key 对应 TimeEvent 中的事件 key
+func NewCalendar(key string, sessions ...Session) *Calendar {
+	return &Calendar{
+		Key:      key,
+		Sessions: sessions,
+		holidays: make(custom.Set[string]),
+	}
+}
+
+// SetLocation 按 IANA 名称设置交易所时区，如 Asia/Shanghai、America/Chicago、Europe/London
+func (cal *Calendar) SetLocation(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid location %q: %w", name, err)
+	}
+	cal.Location = loc
+	return nil
+}
+
+func (cal *Calendar) location() *time.Location {
+	if cal.Location != nil {
+		return cal.Location
+	}
+	return time.Local
+}
+
+// dayOf 返回 t 在日历时区中所在日的零点
+func (cal *Calendar) dayOf(t time.Time) time.Time {
+	return misc.ZeroByTime(t.In(cal.location()))
+}
+
+// at 返回 day 当地零点起墙上时间偏移 offset 的时刻，跨夏令时切换时不按绝对时长累加
+func (cal *Calendar) at(day time.Time, offset time.Duration) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, 0, 0, 0, int(offset), cal.location())
+}
+
+// AddHoliday 添加休市日，按日历时区取日期
+func (cal *Calendar) AddHoliday(days ...time.Time) {
+	for _, day := range days {
+		cal.holidays.Add(misc.DayFormat(day.In(cal.location())))
+	}
+}
+
+// LoadHolidays 从文件加载休市日，每行一个 20060102 格式日期，# 之后为注释
+func (cal *Calendar) LoadHolidays(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open holidays file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if _, err := time.Parse(misc.LayoutDay, text); err != nil {
+			return fmt.Errorf("invalid holiday at line %d: %q", line, text)
+		}
+		cal.holidays.Add(text)
+	}
+	return scanner.Err()
+}
+
+// IsTradingDay 是否为交易日（非周末且非休市日）
+func (cal *Calendar) IsTradingDay(day time.Time) bool {
+	day = day.In(cal.location())
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	return !cal.holidays.Has(misc.DayFormat(day))
+}
+
+// NextTradingDay 返回 day 之后（不含）的第一个交易日零点
+func (cal *Calendar) NextTradingDay(day time.Time) time.Time {
+	d := cal.dayOf(day)
+	for {
+		d = d.AddDate(0, 0, 1)
+		if cal.IsTradingDay(d) {
+			return d
+		}
+	}
+}
+
+// PrevTradingDay 返回 day 之前（不含）的最后一个交易日零点
+func (cal *Calendar) PrevTradingDay(day time.Time) time.Time {
+	d := cal.dayOf(day)
+	for {
+		d = d.AddDate(0, 0, -1)
+		if cal.IsTradingDay(d) {
+			return d
+		}
+	}
+}
+
+// hasNight 长假前的最后一个交易日没有夜盘
+func (cal *Calendar) hasNight(day time.Time) bool {
+	prev := cal.PrevTradingDay(day)
+	for d := prev.AddDate(0, 0, 1); d.Before(day); d = d.AddDate(0, 0, 1) {
+		if cal.holidays.Has(misc.DayFormat(d)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Events 展开指定交易日的全部事件，按事件时间排序；非交易日返回 nil
+func (cal *Calendar) Events(day time.Time) []CalendarEvent {
+	day = cal.dayOf(day)
+	if !cal.IsTradingDay(day) {
+		return nil
+	}
+
+	type span struct {
+		name       string
+		start, end time.Time
+	}
+	spans := make([]span, 0, len(cal.Sessions))
+	for _, s := range cal.Sessions {
+		base := day
+		if s.Night {
+			if !cal.hasNight(day) {
+				continue
+			}
+			base = cal.PrevTradingDay(day)
+		}
+		start := cal.at(base, s.Start)
+		end := cal.at(base, s.End)
+		if s.End <= s.Start {
+			end = cal.at(base.AddDate(0, 0, 1), s.End)
+		}
+		spans = append(spans, span{name: s.Name, start: start, end: end})
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	events := make([]CalendarEvent, 0, 2*len(spans)+1)
+	for _, s := range spans {
+		events = append(events,
+			CalendarEvent{Key: cal.Key, EventType: EventOpenAllow, EventTime: s.start, Dur: cal.OpenLag, Session: s.name},
+			CalendarEvent{Key: cal.Key, EventType: EventCloseProhibit, EventTime: s.end, Dur: cal.CloseLead, Session: s.name},
+		)
+	}
+	last := spans[len(spans)-1]
+	events = append(events, CalendarEvent{Key: cal.Key, EventType: EventForceClose, EventTime: last.end, Dur: cal.ForceLead, Session: last.name})
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].triggerTime().Before(events[j].triggerTime()) })
+	return events
+}
+
+func (e CalendarEvent) triggerTime() time.Time {
+	if e.EventType == EventOpenAllow {
+		return e.EventTime.Add(e.Dur)
+	}
+	return e.EventTime.Add(-e.Dur)
+}
+
+// AddCalendar 将日历自 from 起 days 个交易日的事件加入队列
+// 触发时间早于 from 的事件被忽略，cb 绑定到加入的每个事件，日历时区同时设为该 key 的时区
+func (ttm *TimeEvent) AddCalendar(cal *Calendar, from time.Time, days int, cb func(int)) error {
+	ttm.SetLocation(cal.Key, cal.location())
+
+	day := cal.dayOf(from)
+	if !cal.IsTradingDay(day) {
+		day = cal.NextTradingDay(day)
+	}
+
+	for i := 0; i < days; i++ {
+		for _, e := range cal.Events(day) {
+			if e.triggerTime().Before(from) {
+				continue
+			}
+			if err := ttm.AddEvent(e.Key, e.EventType, e.EventTime, e.Dur, cb); err != nil {
+				return fmt.Errorf("calendar %s add event on %s: %w", cal.Key, misc.DayFormat(day), err)
+			}
+		}
+		day = cal.NextTradingDay(day)
+	}
+	return nil
+}
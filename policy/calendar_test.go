@@ -0,0 +1,192 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func shfeCalendar() *Calendar {
+	cal := NewCalendar("rb",
+		Session{Name: "night", Start: 21 * time.Hour, End: 23 * time.Hour, Night: true},
+		Session{Name: "am1", Start: 9 * time.Hour, End: 10*time.Hour + 15*time.Minute},
+		Session{Name: "am2", Start: 10*time.Hour + 30*time.Minute, End: 11*time.Hour + 30*time.Minute},
+		Session{Name: "pm", Start: 13*time.Hour + 30*time.Minute, End: 15 * time.Hour},
+	)
+	cal.OpenLag = time.Minute
+	cal.CloseLead = time.Minute
+	cal.ForceLead = 5 * time.Minute
+	return cal
+}
+
+func TestCalendarEvents(t *testing.T) {
+	cal := shfeCalendar()
+	monday := time.Date(2025, 9, 29, 0, 0, 0, 0, time.Local)
+
+	events := cal.Events(monday)
+	if len(events) != 9 {
+		t.Fatalf("got %d events, want 9", len(events))
+	}
+
+	// Monday's night session opens on Friday evening
+	first := events[0]
+	if first.EventType != EventOpenAllow || first.Session != "night" ||
+		!first.triggerTime().Equal(time.Date(2025, 9, 26, 21, 1, 0, 0, time.Local)) {
+		t.Fatalf("unexpected first event %+v", first)
+	}
+
+	last := events[len(events)-1]
+	if last.EventType != EventCloseProhibit || !last.triggerTime().Equal(time.Date(2025, 9, 29, 14, 59, 0, 0, time.Local)) {
+		t.Fatalf("unexpected last event %+v", last)
+	}
+	force := events[len(events)-2]
+	if force.EventType != EventForceClose || !force.triggerTime().Equal(time.Date(2025, 9, 29, 14, 55, 0, 0, time.Local)) {
+		t.Fatalf("unexpected force close %+v", force)
+	}
+
+	if cal.Events(monday.AddDate(0, 0, -2)) != nil {
+		t.Fatal("saturday must not have events")
+	}
+}
+
+func TestCalendarHolidays(t *testing.T) {
+	cal := shfeCalendar()
+	file := filepath.Join(t.TempDir(), "holidays.txt")
+	content := "# national day\n20251001\n20251002 # thursday\n\n20251003\n20251006\n20251007\n20251008\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cal.LoadHolidays(file); err != nil {
+		t.Fatal(err)
+	}
+
+	sept30 := time.Date(2025, 9, 30, 0, 0, 0, 0, time.Local)
+	next := cal.NextTradingDay(sept30)
+	if !next.Equal(time.Date(2025, 10, 9, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("next trading day = %s", next)
+	}
+
+	// no night session right after a long holiday
+	for _, e := range cal.Events(next) {
+		if e.Session == "night" {
+			t.Fatalf("unexpected night event %+v", e)
+		}
+	}
+
+	if err := os.WriteFile(file, []byte("2025-10-01\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cal.LoadHolidays(file); err == nil {
+		t.Fatal("expected error for malformed date")
+	}
+}
+
+func TestAddCalendarSkipsPastEvents(t *testing.T) {
+	ttm := NewTimeEvent(false)
+	cal := shfeCalendar()
+
+	from := time.Now()
+	if err := ttm.AddCalendar(cal, from, 3, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ttm.mu.RLock()
+	defer ttm.mu.RUnlock()
+	if len(ttm.eventQueue) == 0 {
+		t.Fatal("no events scheduled")
+	}
+	for _, e := range ttm.eventQueue {
+		if e.timestamp.Before(from) {
+			t.Fatalf("past event scheduled at %s", e.timestamp)
+		}
+	}
+}
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestCalendarAcrossDST(t *testing.T) {
+	chicago := loadLocation(t, "America/Chicago")
+	cme := NewCalendar("es", Session{Name: "rth", Start: 8*time.Hour + 30*time.Minute, End: 15*time.Hour + 15*time.Minute})
+	cme.Location = chicago
+
+	// US daylight saving time starts on Sunday 2025-03-09
+	for _, c := range []struct {
+		day  time.Time
+		open time.Time
+	}{
+		{time.Date(2025, 3, 7, 0, 0, 0, 0, chicago), time.Date(2025, 3, 7, 14, 30, 0, 0, time.UTC)},
+		{time.Date(2025, 3, 10, 0, 0, 0, 0, chicago), time.Date(2025, 3, 10, 13, 30, 0, 0, time.UTC)},
+		{time.Date(2025, 10, 31, 0, 0, 0, 0, chicago), time.Date(2025, 10, 31, 13, 30, 0, 0, time.UTC)},
+		{time.Date(2025, 11, 3, 0, 0, 0, 0, chicago), time.Date(2025, 11, 3, 14, 30, 0, 0, time.UTC)},
+	} {
+		events := cme.Events(c.day)
+		if len(events) != 3 || !events[0].EventTime.Equal(c.open) {
+			t.Fatalf("%s: unexpected events %+v", c.day.Format(time.DateOnly), events)
+		}
+	}
+
+	// on the transition day itself offsets follow the wall clock, not elapsed time
+	sunday := time.Date(2025, 3, 9, 0, 0, 0, 0, chicago)
+	if got := cme.at(sunday, 8*time.Hour+30*time.Minute); got.Hour() != 8 || got.Minute() != 30 {
+		t.Fatalf("session start on DST day at %s", got)
+	}
+
+	// the trading day is taken in the exchange's zone, not the caller's
+	mondayInShanghai := time.Date(2025, 3, 11, 6, 0, 0, 0, loadLocation(t, "Asia/Shanghai"))
+	if events := cme.Events(mondayInShanghai); len(events) == 0 || events[0].EventTime.Day() != 10 {
+		t.Fatalf("unexpected trading day for %s: %+v", mondayInShanghai, events)
+	}
+
+	if err := cme.SetLocation("Not/AZone"); err == nil {
+		t.Fatal("expected error for unknown location")
+	}
+}
+
+func TestMultipleExchangeCalendars(t *testing.T) {
+	shanghai := loadLocation(t, "Asia/Shanghai")
+	london := loadLocation(t, "Europe/London")
+
+	shfe := shfeCalendar()
+	shfe.Location = shanghai
+	lme := NewCalendar("ca", Session{Name: "ring", Start: 11*time.Hour + 40*time.Minute, End: 17 * time.Hour})
+	lme.Location = london
+	lme.ForceLead = 10 * time.Minute
+
+	// UK clocks go back on Sunday 2025-10-26, Shanghai has no daylight saving time
+	from := time.Date(2025, 10, 24, 0, 0, 0, 0, time.UTC)
+	ttm := NewTimeEventWithClock(false, NewManualClock(from))
+	if err := ttm.AddCalendar(shfe, from, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ttm.AddCalendar(lme, from, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ttm.Location("ca") != london || ttm.Location("rb") != shanghai {
+		t.Fatal("calendar location not bound to key")
+	}
+
+	var closes []time.Time
+	for _, e := range ttm.Pending() {
+		if e.Key == "ca" && e.Type == EventForceClose {
+			if e.TriggerTime.Location() != london {
+				t.Fatalf("event time not in exchange zone: %s", e.TriggerTime)
+			}
+			closes = append(closes, e.TriggerTime)
+		}
+	}
+	want := []time.Time{
+		time.Date(2025, 10, 24, 15, 50, 0, 0, time.UTC),
+		time.Date(2025, 10, 27, 16, 50, 0, 0, time.UTC),
+	}
+	if len(closes) != 2 || !closes[0].Equal(want[0]) || !closes[1].Equal(want[1]) {
+		t.Fatalf("unexpected LME force close times %v", closes)
+	}
+}
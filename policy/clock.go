@@ -0,0 +1,165 @@
+package policy
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 时间源，TimeEvent 通过它获取当前时间与创建定时器
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer 定时器
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// RealClock 系统时钟
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) NewTimer(d time.Duration) Timer { return &realTimer{t: time.NewTimer(d)} }
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (rt *realTimer) C() <-chan time.Time        { return rt.t.C }
+func (rt *realTimer) Stop() bool                 { return rt.t.Stop() }
+func (rt *realTimer) Reset(d time.Duration) bool { return rt.t.Reset(d) }
+
+// ManualClock 手动推进的时钟，用于测试与回测
+// Set/Advance 推进时间时向到期定时器的通道发送时间
+// 定时器按单调时间计时，Jump 只改变墙上时间，用于模拟 NTP 校时与虚拟机挂起
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	mono   time.Duration // 单调时间，只增不减
+	timers []*manualTimer
+}
+
+// NewManualClock 创建起始于 now 的手动时钟
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (mc *ManualClock) Now() time.Time {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.now
+}
+
+// Advance 时间前进 d
+func (mc *ManualClock) Advance(d time.Duration) {
+	mc.Set(mc.Now().Add(d))
+}
+
+// Set 设置当前时间，向前推进时单调时间同步前进，向后回拨时仅改变墙上时间
+func (mc *ManualClock) Set(now time.Time) {
+	mc.mu.Lock()
+	if d := now.Sub(mc.now); d > 0 {
+		mc.mono += d
+	}
+	mc.now = now
+	var due []*manualTimer
+	for _, mt := range mc.timers {
+		if mt.active && mt.when <= mc.mono {
+			mt.active = false
+			due = append(due, mt)
+		}
+	}
+	mc.compact()
+	mc.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].when < due[j].when })
+	for _, mt := range due {
+		mt.fire(now)
+	}
+}
+
+// Jump 墙上时间跳变 d，单调时间与定时器不受影响
+func (mc *ManualClock) Jump(d time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.now = mc.now.Add(d)
+}
+
+func (mc *ManualClock) NewTimer(d time.Duration) Timer {
+	mt := &manualTimer{clock: mc, c: make(chan time.Time, 1)}
+	mt.Reset(d)
+	return mt
+}
+
+// compact 移除已失效的定时器，调用方需持有 mu
+func (mc *ManualClock) compact() {
+	active := mc.timers[:0]
+	for _, mt := range mc.timers {
+		if mt.active {
+			active = append(active, mt)
+		}
+	}
+	for i := len(active); i < len(mc.timers); i++ {
+		mc.timers[i] = nil
+	}
+	mc.timers = active
+}
+
+type manualTimer struct {
+	clock  *ManualClock
+	when   time.Duration // 到期的单调时间
+	active bool
+	c      chan time.Time
+}
+
+func (mt *manualTimer) C() <-chan time.Time { return mt.c }
+
+func (mt *manualTimer) Stop() bool {
+	mt.clock.mu.Lock()
+	defer mt.clock.mu.Unlock()
+	wasActive := mt.active
+	mt.active = false
+	mt.drain()
+	return wasActive
+}
+
+// drain 丢弃未读取的到期时间，与 Go 1.23 之后 time.Timer 的 Stop/Reset 行为一致
+func (mt *manualTimer) drain() {
+	select {
+	case <-mt.c:
+	default:
+	}
+}
+
+func (mt *manualTimer) Reset(d time.Duration) bool {
+	mc := mt.clock
+	mc.mu.Lock()
+	wasActive := mt.active
+	now := mc.now
+	mt.when = mc.mono + d
+	mt.drain()
+	if d <= 0 {
+		mt.active = false
+		mc.mu.Unlock()
+		mt.fire(now)
+		return wasActive
+	}
+	if !mt.active {
+		mc.timers = append(mc.timers, mt)
+	}
+	mt.active = true
+	mc.mu.Unlock()
+	return wasActive
+}
+
+func (mt *manualTimer) fire(now time.Time) {
+	select {
+	case mt.c <- now:
+	default:
+	}
+}
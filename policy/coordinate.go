@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultCoordinateTTL     = time.Minute
+	DefaultCoordinateTimeout = time.Second
+)
+
+// Coordinator 多实例部署时的触发协调，同一事件只有抢到锁的实例执行回调
+// id 由 key、事件类型名称与事件时间构成，各实例注册相同事件时一致
+type Coordinator interface {
+	Acquire(ctx context.Context, id string, ttl time.Duration) (bool, error)
+}
+
+// CoordinateOption 协调模式配置
+type CoordinateOption struct {
+	Coordinator Coordinator
+	TTL         time.Duration // 锁持有时长，需覆盖各实例的时钟偏差与触发延迟，默认 DefaultCoordinateTTL
+	Timeout     time.Duration // 单次抢锁超时，默认 DefaultCoordinateTimeout
+	FailOpen    bool          // 协调器不可用时仍在本实例触发，默认跳过以避免重复执行
+}
+
+// SetCoordinate 开启协调模式，opt 为 nil 时关闭，每个实例独立触发
+func (ttm *TimeEvent) SetCoordinate(opt *CoordinateOption) {
+	var coordinate *CoordinateOption
+	if opt != nil && opt.Coordinator != nil {
+		o := *opt
+		if o.TTL <= 0 {
+			o.TTL = DefaultCoordinateTTL
+		}
+		if o.Timeout <= 0 {
+			o.Timeout = DefaultCoordinateTimeout
+		}
+		coordinate = &o
+	}
+
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.coordinate = coordinate
+}
+
+// SetFollowerHook 设置协调模式下的跟随通知，事件已由其他实例执行时在本实例调用，代替回调
+func (ttm *TimeEvent) SetFollowerHook(fn func(e Event)) {
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.followerHook = fn
+}
+
+// eventID 事件标识，使用类型名称而非编号，自定义类型在各实例注册顺序不同时仍一致
+func eventID(e Event) string {
+	return fmt.Sprintf("%s:%s:%d", e.Key, e.TypeName, e.EventTime.UnixNano())
+}
+
+// leader 当前实例是否负责触发该事件，未开启协调模式时始终为 true
+// 锁已被其他实例持有时调用 followerHook
+func (ttm *TimeEvent) leader(opt *CoordinateOption, e Event, errorHook func(Event, error), followerHook func(Event)) bool {
+	if opt == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opt.Timeout)
+	defer cancel()
+	ok, err := opt.Coordinator.Acquire(ctx, eventID(e), opt.TTL)
+	if err != nil {
+		if errorHook != nil {
+			errorHook(e, fmt.Errorf("coordinate failed: key=%s, eventType=%d: %w", e.Key, e.Type, err))
+		}
+		return opt.FailOpen
+	}
+	if !ok && followerHook != nil {
+		followerHook(e)
+	}
+	return ok
+}
+
+// RedisCoordinator 基于 Redis SET NX 的协调器，锁到期自动释放
+// 通常通过 redis 包的 NewCoordinator 使用已初始化的连接创建
+type RedisCoordinator struct {
+	client redis.Cmdable
+	prefix string
+	owner  string
+}
+
+// NewRedisCoordinator 创建 Redis 协调器，prefix 用于区分不同业务的锁
+func NewRedisCoordinator(client redis.Cmdable, prefix string) *RedisCoordinator {
+	host, _ := os.Hostname()
+	return &RedisCoordinator{
+		client: client,
+		prefix: prefix,
+		owner:  fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+}
+
+func (rc *RedisCoordinator) Acquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return rc.client.SetNX(ctx, rc.prefix+id, rc.owner, ttl).Result()
+}
+
+// LocalCoordinator 进程内协调器，多个 TimeEvent 共享同一实例即可模拟多实例部署
+type LocalCoordinator struct {
+	mu    sync.Mutex
+	clock Clock
+	locks map[string]time.Time
+}
+
+// NewLocalCoordinator 创建进程内协调器，clock 为 nil 时使用系统时钟
+func NewLocalCoordinator(clock Clock) *LocalCoordinator {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return &LocalCoordinator{clock: clock, locks: make(map[string]time.Time)}
+}
+
+func (lc *LocalCoordinator) Acquire(_ context.Context, id string, ttl time.Duration) (bool, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	now := lc.clock.Now()
+	for k, expire := range lc.locks {
+		if !expire.After(now) {
+			delete(lc.locks, k)
+		}
+	}
+	if _, held := lc.locks[id]; held {
+		return false, nil
+	}
+	lc.locks[id] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,119 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCoordinatedFiringAcrossInstances(t *testing.T) {
+	start := time.Date(2025, 9, 29, 8, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	coordinator := NewLocalCoordinator(clock)
+
+	fired := make([]int, 3)
+	followed := make([]int, 3)
+	instances := make([]*TimeEvent, len(fired))
+	for i := range instances {
+		ttm := NewTimeEventWithClock(false, clock)
+		ttm.SetCoordinate(&CoordinateOption{Coordinator: coordinator, TTL: 10 * time.Minute})
+		n := i
+		ttm.SetFollowerHook(func(e Event) {
+			if e.Key == "rb" && e.Type == EventForceClose {
+				followed[n]++
+			}
+		})
+		for _, eventTime := range []time.Time{start.Add(time.Hour), start.Add(2 * time.Hour)} {
+			if err := ttm.AddEvent("rb", EventForceClose, eventTime, 5*time.Minute, func(int) { fired[n]++ }); err != nil {
+				t.Fatal(err)
+			}
+		}
+		instances[i] = ttm
+	}
+
+	clock.Set(start.Add(3 * time.Hour))
+	for _, ttm := range instances {
+		ttm.CleanExpiredEvents()
+	}
+	total := 0
+	for _, n := range fired {
+		total += n
+	}
+	if total != 2 {
+		t.Fatalf("events fired %d times across instances %v, want 2", total, fired)
+	}
+	// every instance either ran or observed each event
+	for i := range instances {
+		if fired[i]+followed[i] != 2 {
+			t.Fatalf("instance %d fired %d and followed %d events, want 2 in total", i, fired[i], followed[i])
+		}
+	}
+
+	// without coordination every instance fires
+	solo := NewTimeEventWithClock(false, clock)
+	solo.SetCoordinate(&CoordinateOption{Coordinator: coordinator})
+	solo.SetCoordinate(nil)
+	var soloFired int
+	if err := solo.AddEvent("rb", EventForceClose, start.Add(time.Hour), 5*time.Minute, func(int) { soloFired++ }); err != nil {
+		t.Fatal(err)
+	}
+	solo.CleanExpiredEvents()
+	if soloFired != 1 {
+		t.Fatalf("uncoordinated instance fired %d times", soloFired)
+	}
+}
+
+type fakeRedis struct {
+	redis.Cmdable
+	keys map[string]interface{}
+	err  error
+}
+
+func (f *fakeRedis) SetNX(_ context.Context, key string, value interface{}, _ time.Duration) *redis.BoolCmd {
+	if f.err != nil {
+		return redis.NewBoolResult(false, f.err)
+	}
+	if _, ok := f.keys[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	f.keys[key] = value
+	return redis.NewBoolResult(true, nil)
+}
+
+func TestRedisCoordinator(t *testing.T) {
+	client := &fakeRedis{keys: make(map[string]interface{})}
+	rc := NewRedisCoordinator(client, "policy:event:")
+	e := Event{Key: "rb", TypeName: "ForceClose", EventTime: time.Date(2025, 9, 29, 15, 0, 0, 0, time.UTC)}
+
+	if ok, err := rc.Acquire(context.Background(), eventID(e), time.Minute); !ok || err != nil {
+		t.Fatalf("first acquire: %v %v", ok, err)
+	}
+	if ok, _ := rc.Acquire(context.Background(), eventID(e), time.Minute); ok {
+		t.Fatal("lock acquired twice")
+	}
+	if _, ok := client.keys["policy:event:rb:ForceClose:1759158000000000000"]; !ok {
+		t.Fatalf("unexpected lock keys %v", client.keys)
+	}
+
+	// Redis unavailable: skip by default, fire locally with FailOpen
+	client.err = errors.New("connection refused")
+	for _, failOpen := range []bool{false, true} {
+		clock := NewManualClock(e.EventTime)
+		ttm := NewTimeEventWithClock(false, clock)
+		var reported error
+		ttm.SetErrorHook(func(_ Event, err error) { reported = err })
+		ttm.SetCoordinate(&CoordinateOption{Coordinator: rc, FailOpen: failOpen})
+		fired := false
+		if err := ttm.AddEvent("au", EventOpenAllow, e.EventTime.Add(time.Hour), 0, func(int) { fired = true }); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Hour)
+		ttm.CleanExpiredEvents()
+		if fired != failOpen || !errors.Is(reported, client.err) {
+			t.Fatalf("failOpen=%v: fired=%v reported=%v", failOpen, fired, reported)
+		}
+	}
+}
@@ -2,14 +2,13 @@ package policy
 
 import (
 	"container/heap"
-	"encoding/json"
 	"fmt"
-	"os"
+	"sort"
 	"sync"
 	"time"
 )
 
-// 事件类型
+// 内置事件类型，自定义类型通过 RegisterEventType 注册
 const (
 	EventOpenAllow = iota
 	EventCloseProhibit
@@ -21,9 +20,14 @@ const MaxEventQueueSize = 4092
 
 // ScheduledEvent 定时事件
 type ScheduledEvent struct {
-	timestamp time.Time
+	timestamp time.Time // 触发时间
+	eventTime time.Time // 原始事件时间，与 key、eventType 共同构成去重标识
 	key       string
 	eventType int
+	callback  string        // 回调注册名，用于持久化后重新绑定
+	fn        func(int)     // AddEvent/AddRecurring 传入的回调，不持久化
+	schedule  Schedule      // 周期事件的调度规则，一次性事件为 nil
+	dur       time.Duration // 周期事件重新计算触发时间使用的偏移
 	index     int
 }
 
@@ -60,24 +64,58 @@ type TimeEvent struct {
 	mu           sync.RWMutex
 	callbackMu   sync.RWMutex
 	eventQueue   EventQueue
-	eventChan    chan *ScheduledEvent
+	lookup       map[string]map[int][]*ScheduledEvent // key -> 类型 -> 队列中的事件
+	wakeChan     chan struct{}
 	stopChan     chan struct{}
 	running      bool
-	callbacks    map[string]map[int]func(int) // 支持多个回调
+	callbacks    map[string]map[int]func(int)    // key/类型最近一次 AddEvent 传入的回调，只用于 LoadEvents 恢复的未命名事件
+	subscribers  map[string]map[int][]subscriber // Subscribe 注册的回调，支持多个
+	subSeq       uint64
+	registry     map[string]func(int) // 按名称注册的回调
+	eventTypes   map[int]eventTypeDef // 事件类型，由 callbackMu 保护
+	nextType     int
 	isIO         bool
 	maxQueueSize int
+	clock        Clock
+	executor     Executor                                       // 回调执行器，由 callbackMu 保护
+	errorHook    func(Event, error)                             // 回调 panic 或执行器拒绝时调用
+	latencyHook  func(e Event, lateness, latency time.Duration) // 回调执行后调用
+	locations    map[string]*time.Location                      // key 所属交易所时区，由 callbackMu 保护
+	coordinate   *CoordinateOption                              // 多实例协调，由 callbackMu 保护
+	followerHook func(e Event)                                  // 协调模式下事件由其他实例执行时调用
+	missed       MissedOption                                   // 错过事件处理，由 mu 保护
+	skipHook     func(e Event, late time.Duration)              // 事件被跳过时调用
+	jumpHook     func(delta time.Duration)                      // 检测到时钟跳变时调用
 }
 
 // NewTimeEvent 创建新的交易时间管理器
 func NewTimeEvent(isIO bool) *TimeEvent {
+	return NewTimeEventWithClock(isIO, RealClock{})
+}
+
+// NewTimeEventWithClock 使用指定时钟创建交易时间管理器，测试与回测时传入 ManualClock
+// 回测可不调用 Start，推进时钟后调用 CleanExpiredEvents 同步触发到期事件
+func NewTimeEventWithClock(isIO bool, clock Clock) *TimeEvent {
+	if clock == nil {
+		clock = RealClock{}
+	}
 	return &TimeEvent{
 		eventQueue:   make(EventQueue, 0),
-		eventChan:    make(chan *ScheduledEvent, 1024), // 缓冲通道，处理高并发
+		lookup:       make(map[string]map[int][]*ScheduledEvent),
+		wakeChan:     make(chan struct{}, 1),
 		stopChan:     make(chan struct{}),
 		running:      false,
 		callbacks:    make(map[string]map[int]func(int)),
+		subscribers:  make(map[string]map[int][]subscriber),
+		registry:     make(map[string]func(int)),
+		eventTypes:   builtinEventTypes(),
+		nextType:     EventForceClose + 1,
 		isIO:         isIO,
 		maxQueueSize: MaxEventQueueSize,
+		clock:        clock,
+		executor:     InlineExecutor{},
+		locations:    make(map[string]*time.Location),
+		missed:       DefaultMissedOption(),
 	}
 }
 
@@ -94,48 +132,129 @@ func (ttm *TimeEvent) Start() {
 	go ttm.eventLoop()
 }
 
-// Stop 停止管理器
+// Stop 停止管理器，触发剩余的一次性事件，周期事件留在队列中
 func (ttm *TimeEvent) Stop() {
 	ttm.mu.Lock()
-	defer ttm.mu.Unlock()
-
 	if !ttm.running {
+		ttm.mu.Unlock()
 		return
 	}
 
 	close(ttm.stopChan)
 	ttm.running = false
 
-	// 清理剩余事件并触发回调
+	// 清理剩余的一次性事件，释放锁后触发回调；周期事件没有最后一次，重新入队，可通过 SaveEvents 保存
+	batch := make([]*ScheduledEvent, 0, len(ttm.eventQueue))
+	var recurring []*ScheduledEvent
 	for len(ttm.eventQueue) > 0 {
-		event := heap.Pop(&ttm.eventQueue).(*ScheduledEvent)
-		ttm.fireCallbacks(event.key, event.eventType)
+		event := ttm.pop()
+		if event.schedule != nil {
+			recurring = append(recurring, event)
+			continue
+		}
+		batch = append(batch, event)
+	}
+	for _, event := range recurring {
+		ttm.push(event)
+	}
+	ttm.mu.Unlock()
+
+	for _, event := range batch {
+		ttm.fire(event)
+	}
+}
+
+// SetExecutor 设置回调执行器，默认 InlineExecutor
+func (ttm *TimeEvent) SetExecutor(executor Executor) {
+	if executor == nil {
+		executor = InlineExecutor{}
+	}
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.executor = executor
+}
+
+// SetErrorHook 设置回调 panic 或执行器拒绝任务时的错误回调
+func (ttm *TimeEvent) SetErrorHook(fn func(Event, error)) {
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.errorHook = fn
+}
+
+// SetLatencyHook 设置回调耗时统计，lateness 为开始执行相对计划触发时间的延迟，latency 为执行耗时
+func (ttm *TimeEvent) SetLatencyHook(fn func(e Event, lateness, latency time.Duration)) {
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.latencyHook = fn
+}
+
+// SetLocation 设置 key 所属交易所的时区，周期规则按该时区的当地时间计算，
+// 回调收到的 Event 时间也转换到该时区；loc 为 nil 时恢复为 time.Local
+func (ttm *TimeEvent) SetLocation(key string, loc *time.Location) {
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	if loc == nil {
+		delete(ttm.locations, key)
+		return
+	}
+	ttm.locations[key] = loc
+}
+
+// Location 返回 key 所属时区，未设置时为 time.Local
+func (ttm *TimeEvent) Location(key string) *time.Location {
+	ttm.callbackMu.RLock()
+	defer ttm.callbackMu.RUnlock()
+	return ttm.location(key)
+}
+
+// location 调用方需持有 callbackMu
+func (ttm *TimeEvent) location(key string) *time.Location {
+	if loc, ok := ttm.locations[key]; ok {
+		return loc
 	}
+	return time.Local
 }
 
-// AddEvent 添加事件并注册回调
+// AddEvent 添加事件并注册回调，回调只绑定到该事件，同一 key/类型的多个事件可各自使用不同的回调
+// 事件已存在时保留原回调
 func (ttm *TimeEvent) AddEvent(key string, eventType int, eventTime time.Time, dur time.Duration, cb func(int)) error {
 	ttm.mu.Lock()
 	defer ttm.mu.Unlock()
 
+	return ttm.addEvent(key, eventType, eventTime, dur, cb, "")
+}
+
+// AddNamedEvent 添加事件并绑定按名称注册的回调，保存后可在重启时恢复绑定
+// 回调只绑定到该事件，同一 key/类型的其他事件不受影响
+func (ttm *TimeEvent) AddNamedEvent(key string, eventType int, eventTime time.Time, dur time.Duration, name string) error {
+	ttm.mu.Lock()
+	defer ttm.mu.Unlock()
+
+	ttm.callbackMu.RLock()
+	_, ok := ttm.registry[name]
+	ttm.callbackMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("callback not registered: %s", name)
+	}
+
+	return ttm.addEvent(key, eventType, eventTime, dur, nil, name)
+}
+
+func (ttm *TimeEvent) addEvent(key string, eventType int, eventTime time.Time, dur time.Duration, cb func(int), name string) error {
 	// 参数校验
-	if key == "" || eventType < EventOpenAllow || eventType > EventForceClose {
+	ttm.callbackMu.RLock()
+	def, ok := ttm.eventTypes[eventType]
+	ttm.callbackMu.RUnlock()
+	if key == "" || !ok {
 		return fmt.Errorf("invalid key or event type: key=%s, eventType=%d", key, eventType)
 	}
 	if dur < 0 {
 		return fmt.Errorf("duration cannot be negative: %v", dur)
 	}
 
-	// 注册回调函数（支持多个回调）
+	// 回调记录在事件上，按名称绑定的回调触发时再查找
 	if cb != nil {
-		ttm.callbackMu.Lock()
-		m, ok := ttm.callbacks[key]
-		if !ok {
-			m = make(map[int]func(int))
-		}
-		m[eventType] = cb
-		ttm.callbacks[key] = m
-		ttm.callbackMu.Unlock()
+		ttm.bindCallback(key, eventType, cb)
 	}
 
 	// 检查队列大小
@@ -144,182 +263,346 @@ func (ttm *TimeEvent) AddEvent(key string, eventType int, eventTime time.Time, d
 	}
 
 	// 去重逻辑
-	for _, event := range ttm.eventQueue {
-		if event.key == key && event.eventType == eventType && event.timestamp.Equal(eventTime) {
-			return nil // 事件已存在，忽略
-		}
+	if ttm.hasEvent(key, eventType, eventTime) {
+		return nil // 事件已存在，忽略
 	}
 
-	now := time.Now()
 	// 计算触发时间
-	var triggerTime time.Time
-	switch eventType {
-	case EventOpenAllow:
-		triggerTime = eventTime.Add(dur)
-	case EventCloseProhibit, EventForceClose:
-		triggerTime = eventTime.Add(-dur)
-	default:
-		return fmt.Errorf("unknown event type: %d", eventType)
+	event := &ScheduledEvent{
+		timestamp: def.offset(eventTime, dur),
+		eventTime: eventTime,
+		key:       key,
+		eventType: eventType,
+		callback:  name,
+		fn:        cb,
 	}
 
-	// 添加事件到队列（仅当事件未过期）
-	if now.Before(triggerTime) {
-		event := &ScheduledEvent{
-			timestamp: triggerTime,
-			key:       key,
-			eventType: eventType,
+	// 已过期事件同样入队，由事件循环按错过策略处理
+	ttm.push(event)
+	ttm.wake()
+	return nil
+}
+
+// hasEvent 队列中是否已存在相同标识的事件，调用方需持有 mu
+func (ttm *TimeEvent) hasEvent(key string, eventType int, eventTime time.Time) bool {
+	return ttm.findEvent(key, eventType, eventTime) != nil
+}
+
+// findEvent 调用方需持有 mu
+func (ttm *TimeEvent) findEvent(key string, eventType int, eventTime time.Time) *ScheduledEvent {
+	for _, event := range ttm.lookup[key][eventType] {
+		if event.eventTime.Equal(eventTime) {
+			return event
 		}
-		heap.Push(&ttm.eventQueue, event)
-		select {
-		case ttm.eventChan <- event:
-		default:
-			// 通道满时直接处理
-			ttm.fireCallbacks(key, eventType)
+	}
+	return nil
+}
+
+// push、pop、remove 维护堆与索引，调用方需持有 mu
+func (ttm *TimeEvent) push(event *ScheduledEvent) {
+	heap.Push(&ttm.eventQueue, event)
+	m, ok := ttm.lookup[event.key]
+	if !ok {
+		m = make(map[int][]*ScheduledEvent)
+		ttm.lookup[event.key] = m
+	}
+	m[event.eventType] = append(m[event.eventType], event)
+}
+
+func (ttm *TimeEvent) pop() *ScheduledEvent {
+	event := heap.Pop(&ttm.eventQueue).(*ScheduledEvent)
+	ttm.unindex(event)
+	return event
+}
+
+func (ttm *TimeEvent) remove(event *ScheduledEvent) {
+	heap.Remove(&ttm.eventQueue, event.index)
+	ttm.unindex(event)
+}
+
+func (ttm *TimeEvent) unindex(event *ScheduledEvent) {
+	events := ttm.lookup[event.key][event.eventType]
+	for i, e := range events {
+		if e == event {
+			events = append(events[:i:i], events[i+1:]...)
+			break
 		}
-	} else {
-		// 过期事件直接触发回调
-		time.AfterFunc(5*time.Second, func() {
-			ttm.fireCallbacks(key, eventType)
-		})
 	}
+	if len(events) > 0 {
+		ttm.lookup[event.key][event.eventType] = events
+		return
+	}
+	delete(ttm.lookup[event.key], event.eventType)
+	if len(ttm.lookup[event.key]) == 0 {
+		delete(ttm.lookup, event.key)
+	}
+}
 
-	return nil
+// popDue 取出到期事件，周期事件重新入队下一次触发，调用方需持有 mu
+func (ttm *TimeEvent) popDue(now time.Time) []*ScheduledEvent {
+	var batch []*ScheduledEvent
+	for len(ttm.eventQueue) > 0 && !ttm.eventQueue[0].timestamp.After(now) {
+		event := ttm.pop()
+		batch = append(batch, event)
+		if event.schedule != nil {
+			ttm.rearm(event, now)
+		}
+	}
+	return batch
 }
 
-// CleanExpiredEvents 清理过期事件
-func (ttm *TimeEvent) CleanExpiredEvents() {
+// RemoveEvent 取消 key 下指定类型的全部待触发事件，返回取消数量
+func (ttm *TimeEvent) RemoveEvent(key string, eventType int) int {
+	ttm.mu.Lock()
+	defer ttm.mu.Unlock()
+
+	events := append([]*ScheduledEvent(nil), ttm.lookup[key][eventType]...)
+	for _, event := range events {
+		ttm.remove(event)
+	}
+	if len(events) > 0 {
+		ttm.wake()
+	}
+	return len(events)
+}
+
+// Reschedule 将原事件时间为 eventTime 的事件改到 newEventTime，触发时间按事件类型的偏移与 dur 重新计算
+// 新触发时间已过期时按错过策略处理，周期事件之后的各次触发也改用 dur
+func (ttm *TimeEvent) Reschedule(key string, eventType int, eventTime, newEventTime time.Time, dur time.Duration) error {
+	if dur < 0 {
+		return fmt.Errorf("duration cannot be negative: %v", dur)
+	}
+
 	ttm.mu.Lock()
 	defer ttm.mu.Unlock()
 
-	now := time.Now()
-	for len(ttm.eventQueue) > 0 && ttm.eventQueue[0].timestamp.Before(now) {
-		event := heap.Pop(&ttm.eventQueue).(*ScheduledEvent)
-		ttm.fireCallbacks(event.key, event.eventType)
+	event := ttm.findEvent(key, eventType, eventTime)
+	if event == nil {
+		return fmt.Errorf("event not found: key=%s, eventType=%d, eventTime=%s", key, eventType, eventTime)
+	}
+
+	// 目标时间已有相同事件，仅移除原事件
+	if !eventTime.Equal(newEventTime) && ttm.hasEvent(key, eventType, newEventTime) {
+		ttm.remove(event)
+		ttm.wake()
+		return nil
 	}
+
+	ttm.callbackMu.RLock()
+	def := ttm.eventTypes[eventType]
+	ttm.callbackMu.RUnlock()
+
+	event.eventTime = newEventTime
+	event.timestamp = def.offset(newEventTime, dur)
+	event.dur = dur
+	heap.Fix(&ttm.eventQueue, event.index)
+	ttm.wake()
+	return nil
 }
 
-// 触发回调函数
-func (ttm *TimeEvent) fireCallbacks(key string, eventType int) {
+// Pending 返回按触发时间排序的待触发事件快照
+func (ttm *TimeEvent) Pending() []Event {
+	ttm.mu.RLock()
+	ttm.callbackMu.RLock()
+	pending := make([]Event, len(ttm.eventQueue))
+	for i, event := range ttm.eventQueue {
+		pending[i] = ttm.payload(event)
+	}
+	ttm.callbackMu.RUnlock()
+	ttm.mu.RUnlock()
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].TriggerTime.Before(pending[j].TriggerTime) })
+	return pending
+}
+
+// NextEvent 返回下一个待触发事件
+func (ttm *TimeEvent) NextEvent() (Event, bool) {
+	ttm.mu.RLock()
+	defer ttm.mu.RUnlock()
+
+	if len(ttm.eventQueue) == 0 {
+		return Event{}, false
+	}
+
 	ttm.callbackMu.RLock()
 	defer ttm.callbackMu.RUnlock()
+	return ttm.payload(ttm.eventQueue[0]), true
+}
 
-	cbs, exists := ttm.callbacks[key]
-	if !exists {
-		return
+// CleanExpiredEvents 清理过期事件，按错过策略触发或跳过
+func (ttm *TimeEvent) CleanExpiredEvents() {
+	ttm.mu.Lock()
+	now := ttm.clock.Now()
+	batch, skipped := ttm.settle(ttm.popDue(now), now)
+	ttm.mu.Unlock()
+
+	ttm.reportSkipped(skipped, now)
+	for _, event := range batch {
+		ttm.fire(event)
 	}
+}
 
-	cb, ok := cbs[eventType]
+// wake 通知事件循环重新计算定时器
+func (ttm *TimeEvent) wake() {
+	select {
+	case ttm.wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+// bindCallback 设置 key/类型的回调，供恢复的未命名事件使用
+func (ttm *TimeEvent) bindCallback(key string, eventType int, cb func(int)) {
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.setCallback(key, eventType, cb)
+}
+
+// setCallback 调用方需持有 callbackMu
+func (ttm *TimeEvent) setCallback(key string, eventType int, cb func(int)) {
+	m, ok := ttm.callbacks[key]
 	if !ok {
+		m = make(map[int]func(int))
+		ttm.callbacks[key] = m
+	}
+	m[eventType] = cb
+}
+
+// payload 调用方需持有 callbackMu
+func (ttm *TimeEvent) payload(event *ScheduledEvent) Event {
+	loc := ttm.location(event.key)
+	return Event{
+		Key:         event.key,
+		Type:        event.eventType,
+		TypeName:    ttm.eventTypes[event.eventType].name,
+		EventTime:   event.eventTime.In(loc),
+		TriggerTime: event.timestamp.In(loc),
+		Recurring:   event.schedule != nil,
+	}
+}
+
+// 触发回调函数，回调在释放锁后交由执行器执行，调用方不能持有 mu
+func (ttm *TimeEvent) fire(event *ScheduledEvent) {
+	ttm.callbackMu.RLock()
+	cb := event.fn
+	if event.callback != "" {
+		cb = ttm.registry[event.callback]
+	} else if cb == nil {
+		cb = ttm.callbacks[event.key][event.eventType]
+	}
+	subs := append([]subscriber(nil), ttm.subscribers[event.key][event.eventType]...)
+	payload := ttm.payload(event)
+	executor, errorHook, latencyHook := ttm.executor, ttm.errorHook, ttm.latencyHook
+	coordinate, followerHook := ttm.coordinate, ttm.followerHook
+	ttm.callbackMu.RUnlock()
+
+	if cb == nil && len(subs) == 0 {
 		return
 	}
-	// 异步触发回调
-	cb(eventType)
+	if !ttm.leader(coordinate, payload, errorHook, followerHook) {
+		return
+	}
+
+	run := func(fn func()) {
+		task := func() {
+			start := ttm.clock.Now()
+			defer func() {
+				if r := recover(); r != nil && errorHook != nil {
+					errorHook(payload, fmt.Errorf("callback panic: key=%s, eventType=%d: %v", payload.Key, payload.Type, r))
+				}
+				if latencyHook != nil {
+					latencyHook(payload, start.Sub(payload.TriggerTime), ttm.clock.Now().Sub(start))
+				}
+			}()
+			fn()
+		}
+		if err := executor.Execute(task); err != nil && errorHook != nil {
+			errorHook(payload, fmt.Errorf("callback rejected: key=%s, eventType=%d: %w", payload.Key, payload.Type, err))
+		}
+	}
+
+	if cb != nil {
+		run(func() { cb(event.eventType) })
+	}
+	for _, sub := range subs {
+		fn := sub.fn
+		run(func() { fn(payload) })
+	}
 }
 
 // 事件处理循环
+// 定时器按单调时间计时，到期时墙上时间偏离预期超过 Tolerance 即视为时钟跳变：
+// 向前跳变后集中到期的事件按错过策略处理，向后回拨时重新计算周期事件
 func (ttm *TimeEvent) eventLoop() {
-	timer := time.NewTimer(0)
+	timer := ttm.clock.NewTimer(0)
 	defer timer.Stop()
 
+	var (
+		deadline time.Time // 定时器预期到期的墙上时间
+		expired  bool      // 本轮由定时器唤醒
+	)
 	for {
 		ttm.mu.Lock()
 
-		// 批量处理同一时间点的事件
-		now := time.Now()
-		var batch []*ScheduledEvent
-		for len(ttm.eventQueue) > 0 && ttm.eventQueue[0].timestamp.Before(now) {
-			event := heap.Pop(&ttm.eventQueue).(*ScheduledEvent)
-			batch = append(batch, event)
+		now := ttm.clock.Now()
+		var jump time.Duration
+		if expired && !deadline.IsZero() {
+			if delta := now.Round(0).Sub(deadline); delta > ttm.missed.Tolerance || delta < -ttm.missed.Tolerance {
+				jump = delta
+			}
 		}
+		if jump < 0 {
+			ttm.realign(now)
+		}
+
+		// 批量处理同一时间点的事件
+		batch, skipped := ttm.settle(ttm.popDue(now), now)
 
-		// 设置定时器到下一个事件
-		var nextEvent *ScheduledEvent
+		// 设置定时器到下一个事件，并限制最长休眠以发现时钟向前跳变
+		wait := ttm.missed.CheckInterval
 		if len(ttm.eventQueue) > 0 {
-			nextEvent = ttm.eventQueue[0]
-			timer.Reset(nextEvent.timestamp.Sub(now))
+			if d := ttm.eventQueue[0].timestamp.Sub(now); wait <= 0 || d < wait {
+				wait = d
+			}
+		}
+		if wait > 0 {
+			timer.Reset(wait)
+			deadline = now.Round(0).Add(wait)
 		} else {
 			timer.Stop()
+			deadline = time.Time{}
 		}
 		ttm.mu.Unlock()
 
+		if jump != 0 {
+			ttm.callbackMu.RLock()
+			jumpHook := ttm.jumpHook
+			ttm.callbackMu.RUnlock()
+			if jumpHook != nil {
+				jumpHook(jump)
+			}
+		}
+		ttm.reportSkipped(skipped, now)
+
 		// 触发批量事件的回调
 		for _, event := range batch {
-			ttm.fireCallbacks(event.key, event.eventType)
+			ttm.fire(event)
 		}
 
-		select {
-		case <-ttm.stopChan:
-			return
-		case event := <-ttm.eventChan:
-			// 处理通道中的新事件
-			ttm.mu.Lock()
-			if ttm.running && now.Before(event.timestamp) {
-				heap.Push(&ttm.eventQueue, event)
-				if len(ttm.eventQueue) == 1 || event.timestamp.Before(ttm.eventQueue[0].timestamp) {
-					timer.Reset(event.timestamp.Sub(time.Now()))
-				}
+		// 回调耗时计入预期，避免慢回调被误判为时钟跳变
+		if !deadline.IsZero() {
+			if t := ttm.clock.Now().Round(0); t.After(deadline) {
+				deadline = t
 			}
-			ttm.mu.Unlock()
-		case <-timer.C:
-			// 定时器触发后继续循环
 		}
-	}
-}
-
-// SaveEvents 保存事件队列到文件
-func (ttm *TimeEvent) SaveEvents(filename string) error {
-	if !ttm.isIO {
-		return nil
-	}
 
-	ttm.mu.RLock()
-	defer ttm.mu.RUnlock()
-
-	data, err := json.Marshal(ttm.eventQueue)
-	if err != nil {
-		return fmt.Errorf("failed to marshal events: %w", err)
-	}
-
-	// 写入临时文件后重命名，确保原子性
-	tmpFile := filename + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write events to temp file: %w", err)
-	}
-	if err := os.Rename(tmpFile, filename); err != nil {
-		return fmt.Errorf("failed to rename temp file: %w", err)
-	}
-
-	return nil
-}
-
-// LoadEvents 从文件恢复事件队列
-func (ttm *TimeEvent) LoadEvents(filename string) error {
-	if !ttm.isIO {
-		return nil
-	}
-
-	ttm.mu.Lock()
-	defer ttm.mu.Unlock()
-
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("failed to read events file: %w", err)
-	}
-
-	var events []*ScheduledEvent
-	if err := json.Unmarshal(data, &events); err != nil {
-		return fmt.Errorf("failed to unmarshal events: %w", err)
-	}
-
-	// 恢复事件队列，忽略过期事件
-	now := time.Now()
-	for _, event := range events {
-		if event.timestamp.After(now) {
-			heap.Push(&ttm.eventQueue, event)
-		} else {
-			ttm.fireCallbacks(event.key, event.eventType)
+		expired = false
+		select {
+		case <-ttm.stopChan:
+			return
+		case <-ttm.wakeChan:
+			// 有新事件入队，重新计算定时器
+		case <-timer.C():
+			expired = true
 		}
 	}
-
-	return nil
 }
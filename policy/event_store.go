@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// EventFileVersion 事件文件格式版本
+const EventFileVersion = 1
+
+type eventFile struct {
+	Version int           `json:"version"`
+	SavedAt time.Time     `json:"savedAt"`
+	Events  []eventRecord `json:"events"`
+}
+
+type eventRecord struct {
+	Key         string    `json:"key"`
+	EventType   int       `json:"eventType"`
+	TypeName    string    `json:"typeName,omitempty"`
+	EventTime   time.Time `json:"eventTime"`
+	TriggerTime time.Time `json:"triggerTime"`
+	Callback    string    `json:"callback,omitempty"`
+}
+
+// RegisterCallback 按名称注册回调，AddNamedEvent 与 LoadEvents 通过名称绑定
+func (ttm *TimeEvent) RegisterCallback(name string, cb func(int)) error {
+	if name == "" || cb == nil {
+		return fmt.Errorf("invalid callback registration: name=%q", name)
+	}
+
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.registry[name] = cb
+	return nil
+}
+
+// SaveEvents 保存事件队列到文件
+func (ttm *TimeEvent) SaveEvents(filename string) error {
+	if !ttm.isIO {
+		return nil
+	}
+
+	ttm.mu.RLock()
+	ttm.callbackMu.RLock()
+	file := eventFile{
+		Version: EventFileVersion,
+		SavedAt: ttm.clock.Now(),
+		Events:  make([]eventRecord, 0, len(ttm.eventQueue)),
+	}
+	for _, event := range ttm.eventQueue {
+		if event.schedule != nil {
+			continue // 周期事件由调用方重启后重新注册
+		}
+		file.Events = append(file.Events, eventRecord{
+			Key:         event.key,
+			EventType:   event.eventType,
+			TypeName:    ttm.eventTypes[event.eventType].name,
+			EventTime:   event.eventTime,
+			TriggerTime: event.timestamp,
+			Callback:    event.callback,
+		})
+	}
+	ttm.callbackMu.RUnlock()
+	ttm.mu.RUnlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %w", err)
+	}
+
+	// 写入临时文件后重命名，确保原子性
+	tmpFile := filename + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write events to temp file: %w", err)
+	}
+	if err := os.Rename(tmpFile, filename); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadEvents 从文件恢复事件队列，并按回调名称重新绑定回调
+// 回调需在调用前通过 RegisterCallback 注册，已过期事件按错过策略处理
+func (ttm *TimeEvent) LoadEvents(filename string) error {
+	if !ttm.isIO {
+		return nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read events file: %w", err)
+	}
+
+	var file eventFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal events: %w", err)
+	}
+	if file.Version != EventFileVersion {
+		return fmt.Errorf("unsupported events file version: %d", file.Version)
+	}
+
+	// 先校验事件类型与回调，避免恢复一半
+	ttm.callbackMu.RLock()
+	for i, r := range file.Events {
+		if r.TypeName != "" {
+			eventType, ok := ttm.eventTypeByName(r.TypeName)
+			if !ok {
+				ttm.callbackMu.RUnlock()
+				return fmt.Errorf("event type not registered: %s", r.TypeName)
+			}
+			file.Events[i].EventType = eventType
+		} else if _, ok := ttm.eventTypes[r.EventType]; !ok {
+			ttm.callbackMu.RUnlock()
+			return fmt.Errorf("unknown event type: %d", r.EventType)
+		}
+		if r.Callback == "" {
+			continue
+		}
+		if _, ok := ttm.registry[r.Callback]; !ok {
+			ttm.callbackMu.RUnlock()
+			return fmt.Errorf("callback not registered: %s", r.Callback)
+		}
+	}
+	ttm.callbackMu.RUnlock()
+
+	// 恢复事件队列，过期事件由事件循环按错过策略处理
+	ttm.mu.Lock()
+	for _, r := range file.Events {
+		event := &ScheduledEvent{
+			timestamp: r.TriggerTime,
+			eventTime: r.EventTime,
+			key:       r.Key,
+			eventType: r.EventType,
+			callback:  r.Callback,
+		}
+		if ttm.hasEvent(r.Key, r.EventType, r.EventTime) {
+			continue
+		}
+		ttm.push(event)
+	}
+	ttm.mu.Unlock()
+	ttm.wake()
+	return nil
+}
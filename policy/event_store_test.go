@@ -0,0 +1,141 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventsRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.json")
+	base := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	src := NewTimeEvent(true)
+	if err := src.RegisterCallback("forceClose", func(int) {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.AddNamedEvent("rb", EventForceClose, base, 5*time.Minute, "forceClose"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.AddEvent("au", EventOpenAllow, base, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SaveEvents(file); err != nil {
+		t.Fatal(err)
+	}
+
+	fired := make(chan int, 1)
+	dst := NewTimeEvent(true)
+	if err := dst.RegisterCallback("forceClose", func(eventType int) { fired <- eventType }); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.LoadEvents(file); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]*ScheduledEvent)
+	for _, e := range dst.eventQueue {
+		got[e.key] = e
+	}
+	if len(got) != 2 {
+		t.Fatalf("restored %d events, want 2", len(got))
+	}
+	rb := got["rb"]
+	if rb.eventType != EventForceClose || !rb.eventTime.Equal(base) ||
+		!rb.timestamp.Equal(base.Add(-5*time.Minute)) || rb.callback != "forceClose" {
+		t.Fatalf("unexpected rb event %+v", rb)
+	}
+	au := got["au"]
+	if au.eventType != EventOpenAllow || !au.timestamp.Equal(base.Add(time.Minute)) {
+		t.Fatalf("unexpected au event %+v", au)
+	}
+
+	// the restored event is deduplicated against its original identity
+	if err := dst.AddEvent("rb", EventForceClose, base, 5*time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.eventQueue) != 2 {
+		t.Fatalf("duplicate event scheduled, queue size %d", len(dst.eventQueue))
+	}
+
+	// the handler is re-bound by name
+	dst.fire(rb)
+	select {
+	case eventType := <-fired:
+		if eventType != EventForceClose {
+			t.Fatalf("fired with %d", eventType)
+		}
+	default:
+		t.Fatal("restored callback not bound")
+	}
+}
+
+func TestLoadEventsRejectsUnknownData(t *testing.T) {
+	dir := t.TempDir()
+
+	unregistered := filepath.Join(dir, "unregistered.json")
+	src := NewTimeEvent(true)
+	_ = src.RegisterCallback("open", func(int) {})
+	if err := src.AddNamedEvent("rb", EventOpenAllow, time.Now().Add(time.Hour), 0, "open"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SaveEvents(unregistered); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewTimeEvent(true)
+	if err := dst.LoadEvents(unregistered); err == nil {
+		t.Fatal("expected error for unregistered callback")
+	}
+	if len(dst.eventQueue) != 0 {
+		t.Fatal("events restored despite error")
+	}
+
+	future := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(future, []byte(`{"version":99,"events":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.LoadEvents(future); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+}
+
+func TestNamedCallbacksPerEvent(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.json")
+	base := time.Date(2025, 6, 3, 14, 55, 0, 0, time.UTC)
+	clock := NewManualClock(base.Add(-time.Hour))
+
+	var fired []string
+	register := func(ttm *TimeEvent) {
+		for _, name := range []string{"closeLong", "closeShort"} {
+			_ = ttm.RegisterCallback(name, func(int) { fired = append(fired, name) })
+		}
+	}
+
+	src := NewTimeEventWithClock(true, clock)
+	register(src)
+	if err := src.AddNamedEvent("rb", EventForceClose, base, 0, "closeLong"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.AddNamedEvent("rb", EventForceClose, base.Add(time.Minute), 0, "closeShort"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SaveEvents(file); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewTimeEventWithClock(true, clock)
+	register(dst)
+	if err := dst.LoadEvents(file); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(2 * time.Hour)
+	src.CleanExpiredEvents()
+	dst.CleanExpiredEvents()
+	want := []string{"closeLong", "closeShort", "closeLong", "closeShort"}
+	if fmt.Sprint(fired) != fmt.Sprint(want) {
+		t.Fatalf("fired %v, want %v", fired, want)
+	}
+}
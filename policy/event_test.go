@@ -0,0 +1,274 @@
+package policy
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCustomEventTypeSubscribers(t *testing.T) {
+	ttm := NewTimeEvent(false)
+	settle, err := ttm.RegisterEventType("Settle", OffsetAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ttm.RegisterEventType("Settle", OffsetBefore); again != settle {
+		t.Fatalf("re-registration returned %d, want %d", again, settle)
+	}
+	if settle <= EventForceClose || ttm.EventTypeName(settle) != "Settle" {
+		t.Fatalf("unexpected event type %d %q", settle, ttm.EventTypeName(settle))
+	}
+
+	var (
+		mu  sync.Mutex
+		got []string
+		wg  sync.WaitGroup
+	)
+	wg.Add(2)
+	record := func(name string) Handler {
+		return func(e Event) {
+			mu.Lock()
+			got = append(got, name+":"+e.TypeName+":"+e.Key)
+			mu.Unlock()
+			wg.Done()
+		}
+	}
+	if _, err = ttm.Subscribe("rb", settle, record("risk")); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := ttm.Subscribe("rb", settle, record("removed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ttm.Subscribe("rb", settle, record("report")); err != nil {
+		t.Fatal(err)
+	}
+	removed.Unsubscribe()
+	removed.Unsubscribe()
+
+	if _, err = ttm.Subscribe("rb", 99, record("unknown")); err == nil {
+		t.Fatal("expected error for unknown event type")
+	}
+
+	ttm.Start()
+	defer ttm.Stop()
+	if err = ttm.AddEvent("rb", settle, time.Now(), 30*time.Millisecond, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscribers not called")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0] != "risk:Settle:rb" || got[1] != "report:Settle:rb" {
+		t.Fatalf("unexpected calls %v", got)
+	}
+}
+
+func TestAddEventCallbacksDoNotReplace(t *testing.T) {
+	start := time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	var fired []string
+	record := func(name string) func(int) { return func(int) { fired = append(fired, name) } }
+	if err := ttm.AddEvent("rb", EventForceClose, start.Add(time.Hour), 0, record("strategy")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ttm.AddEvent("rb", EventForceClose, start.Add(2*time.Hour), 0, record("risk")); err != nil {
+		t.Fatal(err)
+	}
+	// a duplicate event keeps the callback it was added with
+	if err := ttm.AddEvent("rb", EventForceClose, start.Add(time.Hour), 0, record("duplicate")); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(2 * time.Hour)
+	ttm.CleanExpiredEvents()
+	if len(fired) != 2 || fired[0] != "strategy" || fired[1] != "risk" {
+		t.Fatalf("fired %v, want strategy then risk", fired)
+	}
+}
+
+func TestRemoveRescheduleAndInspect(t *testing.T) {
+	ttm := NewTimeEvent(false)
+	base := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	for i := 0; i < 3; i++ {
+		day := base.AddDate(0, 0, i)
+		if err := ttm.AddEvent("rb", EventForceClose, day, 5*time.Minute, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := ttm.AddEvent("rb", EventOpenAllow, day.Add(-30*time.Minute), time.Minute, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ttm.AddEvent("au", EventCloseProhibit, base, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := ttm.Pending()
+	if len(pending) != 7 {
+		t.Fatalf("pending %d, want 7", len(pending))
+	}
+	for i := 1; i < len(pending); i++ {
+		if pending[i].TriggerTime.Before(pending[i-1].TriggerTime) {
+			t.Fatal("pending not sorted by trigger time")
+		}
+	}
+
+	if n := ttm.RemoveEvent("rb", EventOpenAllow); n != 3 {
+		t.Fatalf("removed %d, want 3", n)
+	}
+	if n := ttm.RemoveEvent("rb", EventOpenAllow); n != 0 {
+		t.Fatalf("removed %d again", n)
+	}
+
+	// the exchange extends today's session by 30 minutes
+	extended := base.Add(30 * time.Minute)
+	if err := ttm.Reschedule("rb", EventForceClose, base, extended, 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := ttm.Reschedule("rb", EventForceClose, base, extended, 5*time.Minute); err == nil {
+		t.Fatal("expected error for unknown event")
+	}
+
+	next, ok := ttm.NextEvent()
+	if !ok || next.Key != "au" || next.TypeName != "CloseProhibit" {
+		t.Fatalf("unexpected next event %+v", next)
+	}
+
+	pending = ttm.Pending()
+	if len(pending) != 4 {
+		t.Fatalf("pending %d, want 4", len(pending))
+	}
+	if pending[1].Key != "rb" || !pending[1].EventTime.Equal(extended) ||
+		!pending[1].TriggerTime.Equal(extended.Add(-5*time.Minute)) {
+		t.Fatalf("unexpected rescheduled event %+v", pending[1])
+	}
+
+	// heap invariant holds after Fix/Remove
+	for len(ttm.eventQueue) > 0 {
+		if e := ttm.pop(); len(ttm.eventQueue) > 0 && ttm.eventQueue[0].timestamp.Before(e.timestamp) {
+			t.Fatal("heap order broken")
+		}
+	}
+	if len(ttm.lookup) != 0 {
+		t.Fatalf("lookup not cleaned: %v", ttm.lookup)
+	}
+}
+
+func TestManualClockDrivesEventLoop(t *testing.T) {
+	start := time.Date(2025, 9, 29, 8, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	fired := make(chan Event, 1)
+	if _, err := ttm.Subscribe("rb", EventOpenAllow, func(e Event) { fired <- e }); err != nil {
+		t.Fatal(err)
+	}
+	ttm.Start()
+	defer ttm.Stop()
+
+	open := start.Add(time.Hour)
+	if err := ttm.AddEvent("rb", EventOpenAllow, open, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour)
+	select {
+	case e := <-fired:
+		t.Fatalf("fired early %+v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case e := <-fired:
+		if !e.TriggerTime.Equal(open.Add(time.Minute)) {
+			t.Fatalf("unexpected trigger time %s", e.TriggerTime)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not fired after advancing the clock")
+	}
+}
+
+func TestManualClockBacktest(t *testing.T) {
+	day := time.Date(2025, 9, 29, 0, 0, 0, 0, time.Local)
+	clock := NewManualClock(day)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	var got []int
+	cal := shfeCalendar()
+	if err := ttm.AddCalendar(cal, clock.Now(), 1, func(eventType int) { got = append(got, eventType) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// replay the trading day on historical timestamps
+	clock.Set(day.Add(9*time.Hour + 30*time.Minute))
+	ttm.CleanExpiredEvents()
+	if len(got) != 1 || got[0] != EventOpenAllow {
+		t.Fatalf("after open got %v", got)
+	}
+
+	clock.Set(day.Add(15 * time.Hour))
+	ttm.CleanExpiredEvents()
+	if len(got) != 7 || got[5] != EventForceClose || got[6] != EventCloseProhibit {
+		t.Fatalf("after close got %v", got)
+	}
+}
+
+// Events added while the loop runs must neither fire early when many arrive at once
+// nor be enqueued twice; the loop is only woken and re-reads the queue.
+func TestEventsAddedWhileRunningFireOnce(t *testing.T) {
+	start := time.Date(2025, 9, 29, 8, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	var mu sync.Mutex
+	fired := make(map[time.Time]int)
+	ttm.Start()
+	defer ttm.Stop()
+	if _, err := ttm.Subscribe("rb", EventOpenAllow, func(e Event) {
+		mu.Lock()
+		fired[e.EventTime]++
+		mu.Unlock()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 2000 // more than the former 1024-slot event channel
+	for i := 0; i < n; i++ {
+		if err := ttm.AddEvent("rb", EventOpenAllow, start.Add(time.Hour+time.Duration(i)*time.Second), 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	early := len(fired)
+	mu.Unlock()
+	if early != 0 {
+		t.Fatalf("%d events fired before their time", early)
+	}
+
+	clock.Advance(2 * time.Hour)
+	waitFor(t, "all events to fire", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(fired) == n
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	for eventTime, count := range fired {
+		if count != 1 {
+			t.Fatalf("event at %s fired %d times", eventTime, count)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+)
+
+// OffsetFunc 根据事件时间与偏移量计算触发时间
+type OffsetFunc func(eventTime time.Time, dur time.Duration) time.Time
+
+// OffsetAfter 事件时间之后 dur 触发
+func OffsetAfter(eventTime time.Time, dur time.Duration) time.Time { return eventTime.Add(dur) }
+
+// OffsetBefore 事件时间之前 dur 触发
+func OffsetBefore(eventTime time.Time, dur time.Duration) time.Time { return eventTime.Add(-dur) }
+
+type eventTypeDef struct {
+	name   string
+	offset OffsetFunc
+}
+
+// Event 回调载荷
+type Event struct {
+	Key         string
+	Type        int
+	TypeName    string
+	EventTime   time.Time // 原始事件时间
+	TriggerTime time.Time // 计划触发时间
+	Recurring   bool      // 是否为周期事件
+}
+
+// Handler 事件订阅回调
+type Handler func(Event)
+
+type subscriber struct {
+	id uint64
+	fn Handler
+}
+
+// Subscription 订阅句柄，用于取消订阅
+type Subscription struct {
+	ttm       *TimeEvent
+	key       string
+	eventType int
+	id        uint64
+}
+
+func builtinEventTypes() map[int]eventTypeDef {
+	return map[int]eventTypeDef{
+		EventOpenAllow:     {name: "OpenAllow", offset: OffsetAfter},
+		EventCloseProhibit: {name: "CloseProhibit", offset: OffsetBefore},
+		EventForceClose:    {name: "ForceClose", offset: OffsetBefore},
+	}
+}
+
+// RegisterEventType 注册自定义事件类型，返回新的类型值；同名重复注册返回已有类型值
+func (ttm *TimeEvent) RegisterEventType(name string, offset OffsetFunc) (int, error) {
+	if name == "" || offset == nil {
+		return 0, fmt.Errorf("invalid event type registration: name=%q", name)
+	}
+
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+
+	if eventType, ok := ttm.eventTypeByName(name); ok {
+		return eventType, nil
+	}
+	eventType := ttm.nextType
+	ttm.nextType++
+	ttm.eventTypes[eventType] = eventTypeDef{name: name, offset: offset}
+	return eventType, nil
+}
+
+// EventTypeName 返回事件类型名称，未注册时返回空串
+func (ttm *TimeEvent) EventTypeName(eventType int) string {
+	ttm.callbackMu.RLock()
+	defer ttm.callbackMu.RUnlock()
+	return ttm.eventTypes[eventType].name
+}
+
+// eventTypeByName 调用方需持有 callbackMu
+func (ttm *TimeEvent) eventTypeByName(name string) (int, bool) {
+	for eventType, def := range ttm.eventTypes {
+		if def.name == name {
+			return eventType, true
+		}
+	}
+	return 0, false
+}
+
+// Subscribe 订阅 key 下指定类型的事件，同一 key/类型可有多个订阅者
+func (ttm *TimeEvent) Subscribe(key string, eventType int, fn Handler) (*Subscription, error) {
+	if key == "" || fn == nil {
+		return nil, fmt.Errorf("invalid subscription: key=%s", key)
+	}
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+
+	if _, ok := ttm.eventTypes[eventType]; !ok {
+		return nil, fmt.Errorf("unknown event type: %d", eventType)
+	}
+
+	ttm.subSeq++
+	m, ok := ttm.subscribers[key]
+	if !ok {
+		m = make(map[int][]subscriber)
+		ttm.subscribers[key] = m
+	}
+	m[eventType] = append(m[eventType], subscriber{id: ttm.subSeq, fn: fn})
+
+	return &Subscription{ttm: ttm, key: key, eventType: eventType, id: ttm.subSeq}, nil
+}
+
+// Unsubscribe 取消订阅，可重复调用
+func (s *Subscription) Unsubscribe() {
+	s.ttm.callbackMu.Lock()
+	defer s.ttm.callbackMu.Unlock()
+
+	subs := s.ttm.subscribers[s.key][s.eventType]
+	for i, sub := range subs {
+		if sub.id == s.id {
+			s.ttm.subscribers[s.key][s.eventType] = append(subs[:i:i], subs[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrExecutorFull   = errors.New("executor queue is full")
+	ErrExecutorClosed = errors.New("executor is closed")
+)
+
+// Executor 回调执行器
+type Executor interface {
+	Execute(task func()) error
+}
+
+// InlineExecutor 在触发事件的协程中同步执行，慢回调会阻塞调度
+type InlineExecutor struct{}
+
+func (InlineExecutor) Execute(task func()) error {
+	task()
+	return nil
+}
+
+// GoExecutor 每个回调一个协程
+type GoExecutor struct{}
+
+func (GoExecutor) Execute(task func()) error {
+	go task()
+	return nil
+}
+
+// PoolExecutor 固定数量协程与有界队列，队列满时返回 ErrExecutorFull
+type PoolExecutor struct {
+	tasks  chan func()
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPoolExecutor 创建 workers 个协程、队列长度 queueSize 的执行器
+func NewPoolExecutor(workers, queueSize int) *PoolExecutor {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	pe := &PoolExecutor{tasks: make(chan func(), queueSize)}
+	pe.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer pe.wg.Done()
+			for task := range pe.tasks {
+				task()
+			}
+		}()
+	}
+	return pe
+}
+
+func (pe *PoolExecutor) Execute(task func()) error {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	if pe.closed {
+		return ErrExecutorClosed
+	}
+	select {
+	case pe.tasks <- task:
+		return nil
+	default:
+		return ErrExecutorFull
+	}
+}
+
+// Close 停止接收新任务，等待已入队任务执行完毕
+func (pe *PoolExecutor) Close() {
+	pe.mu.Lock()
+	if pe.closed {
+		pe.mu.Unlock()
+		return
+	}
+	pe.closed = true
+	close(pe.tasks)
+	pe.mu.Unlock()
+
+	pe.wg.Wait()
+}
@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCallbackPanicIsolation(t *testing.T) {
+	start := time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	var (
+		errs     []error
+		lateness []time.Duration
+		calls    int
+	)
+	ttm.SetErrorHook(func(e Event, err error) { errs = append(errs, err) })
+	ttm.SetLatencyHook(func(e Event, late, latency time.Duration) { lateness = append(lateness, late) })
+
+	// a callback that re-enters the scheduler must not deadlock
+	err := ttm.AddEvent("rb", EventForceClose, start.Add(time.Hour), 0, func(int) {
+		if err := ttm.AddEvent("rb", EventForceClose, start.Add(25*time.Hour), 0, nil); err != nil {
+			t.Error(err)
+		}
+		panic("broker rejected close order")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ttm.Subscribe("rb", EventForceClose, func(Event) { calls++ }); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour + time.Second)
+	ttm.CleanExpiredEvents()
+
+	if calls != 1 {
+		t.Fatalf("subscriber after panicking callback called %d times", calls)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broker rejected close order") {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if len(lateness) != 2 || lateness[0] != time.Second {
+		t.Fatalf("unexpected lateness %v", lateness)
+	}
+	if next, ok := ttm.NextEvent(); !ok || !next.EventTime.Equal(start.Add(25*time.Hour)) {
+		t.Fatalf("re-entrant AddEvent lost: %+v", next)
+	}
+}
+
+func TestPoolExecutor(t *testing.T) {
+	pe := NewPoolExecutor(2, 1)
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		if err := pe.Execute(func() { defer wg.Done(); <-release }); err != nil {
+			t.Fatalf("task %d rejected: %v", i, err)
+		}
+		// let the workers pick up the first two tasks
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := pe.Execute(func() {}); !errors.Is(err, ErrExecutorFull) {
+		t.Fatalf("err = %v, want ErrExecutorFull", err)
+	}
+
+	close(release)
+	wg.Wait()
+	pe.Close()
+	if err := pe.Execute(func() {}); !errors.Is(err, ErrExecutorClosed) {
+		t.Fatalf("err = %v, want ErrExecutorClosed", err)
+	}
+}
@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHedgeDelay      = 100 * time.Millisecond
+	DefaultHedgePercentile = 0.95
+	DefaultHedgeSamples    = 100
+)
+
+// HedgeOption 对冲请求配置
+type HedgeOption struct {
+	Delay      time.Duration // 延迟样本不足时的对冲阈值，默认 DefaultHedgeDelay
+	Percentile float64       // 以最近成功调用延迟的该分位数作为阈值，(0, 1)，默认 DefaultHedgePercentile
+	Samples    int           // 保留的延迟样本数，默认 DefaultHedgeSamples
+	MinSamples int           // 样本数达到后才按分位数计算阈值，默认 Samples/5
+}
+
+// Hedge 对冲请求：首次请求超过延迟阈值仍未返回时再发出一次相同请求，取先成功的结果
+// 仅适用于幂等的查询（如行情报价），落败请求的结果被丢弃，其 ctx 随调用结束取消
+type Hedge struct {
+	opt     HedgeOption
+	mu      sync.Mutex
+	samples []time.Duration // 环形缓冲
+	next    int
+}
+
+// NewHedge 创建对冲请求执行器
+func NewHedge(opt HedgeOption) *Hedge {
+	if opt.Delay <= 0 {
+		opt.Delay = DefaultHedgeDelay
+	}
+	if opt.Percentile <= 0 || opt.Percentile >= 1 {
+		opt.Percentile = DefaultHedgePercentile
+	}
+	if opt.Samples <= 0 {
+		opt.Samples = DefaultHedgeSamples
+	}
+	if opt.MinSamples <= 0 {
+		opt.MinSamples = max(opt.Samples/5, 1)
+	}
+	return &Hedge{opt: opt, samples: make([]time.Duration, 0, opt.Samples)}
+}
+
+// Threshold 当前对冲阈值
+func (h *Hedge) Threshold() time.Duration {
+	h.mu.Lock()
+	if len(h.samples) < h.opt.MinSamples {
+		h.mu.Unlock()
+		return h.opt.Delay
+	}
+	sorted := slices.Clone(h.samples)
+	h.mu.Unlock()
+
+	slices.Sort(sorted)
+	i := int(math.Ceil(h.opt.Percentile*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
+
+// observe 记录一次成功调用的延迟
+func (h *Hedge) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < h.opt.Samples {
+		h.samples = append(h.samples, latency)
+		return
+	}
+	h.samples[h.next] = latency
+	h.next = (h.next + 1) % h.opt.Samples
+}
+
+// HedgeDo 以对冲方式执行 fn
+func HedgeDo[T any](ctx context.Context, h *Hedge, fn func(ctx context.Context) (T, error)) (T, error) {
+	result, _, err := hedgeDo(ctx, h, fn, nil)
+	return result, err
+}
+
+// hedgeDo 执行 fn，超过阈值后经 gate 放行再发出对冲请求，返回是否发出了对冲请求
+// 两次请求都失败时返回后结束的错误；首次请求在对冲前失败时直接返回，由调用方决定是否重试
+func hedgeDo[T any](ctx context.Context, h *Hedge, fn func(ctx context.Context) (T, error), gate func(ctx context.Context) error) (T, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		result T
+		err    error
+		gated  bool // 对冲请求未获放行，未执行
+	}
+	outcomes := make(chan outcome, 2)
+	start := time.Now()
+	go func() {
+		result, err := fn(ctx)
+		outcomes <- outcome{result: result, err: err}
+	}()
+
+	timer := time.NewTimer(h.Threshold())
+	defer timer.Stop()
+
+	pending, hedged := 1, false
+	var last outcome
+	for {
+		select {
+		case <-timer.C:
+			pending, hedged = 2, true
+			go func() {
+				if gate != nil {
+					if err := gate(ctx); err != nil {
+						outcomes <- outcome{err: err, gated: true}
+						return
+					}
+				}
+				result, err := fn(ctx)
+				outcomes <- outcome{result: result, err: err}
+			}()
+		case o := <-outcomes:
+			pending--
+			if o.err == nil {
+				h.observe(time.Since(start))
+				return o.result, hedged, nil
+			}
+			if o.gated {
+				hedged = false
+			} else {
+				last = o
+			}
+			if pending == 0 {
+				return last.result, hedged, last.err
+			}
+		}
+	}
+}
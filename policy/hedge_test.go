@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestHedgeThreshold(t *testing.T) {
+	h := NewHedge(HedgeOption{Delay: 50 * time.Millisecond, Percentile: 0.9, Samples: 10, MinSamples: 5})
+	for i := 1; i <= 4; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if h.Threshold() != 50*time.Millisecond {
+		t.Fatalf("threshold %v before enough samples", h.Threshold())
+	}
+	for i := 5; i <= 20; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	// only the latest 10 samples (11..20ms) are kept
+	if h.Threshold() != 19*time.Millisecond {
+		t.Fatalf("threshold %v, want p90 of recent samples", h.Threshold())
+	}
+}
+
+func TestHedgeTakesFirstSuccess(t *testing.T) {
+	h := NewHedge(HedgeOption{Delay: 10 * time.Millisecond})
+	var calls atomic.Int32
+	var cancelled atomic.Bool
+	quote := func(ctx context.Context) (float64, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done() // the stuck primary is cancelled once the hedge wins
+			cancelled.Store(true)
+			return 0, ctx.Err()
+		}
+		return 3650.5, nil
+	}
+
+	start := time.Now()
+	price, err := HedgeDo(context.Background(), h, quote)
+	if err != nil || price != 3650.5 {
+		t.Fatalf("got %v, %v", price, err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("hedge fired after %v", elapsed)
+	}
+	waitFor(t, "primary cancellation", cancelled.Load)
+
+	// a fast failure is returned without hedging
+	calls.Store(0)
+	failure := errors.New("rejected")
+	if _, err := HedgeDo(context.Background(), h, func(context.Context) (int, error) { calls.Add(1); return 0, failure }); !errors.Is(err, failure) || calls.Load() != 1 {
+		t.Fatalf("got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestHedgeInPolicyPipeline(t *testing.T) {
+	budget := NewRetryBudget(RetryBudgetOption{MinRetries: 1})
+	lr := NewLR(Policy{RateLimit: rate.Inf, MaxRetries: 1, Budget: budget, Hedge: NewHedge(HedgeOption{Delay: 5 * time.Millisecond})})
+
+	var calls atomic.Int32
+	slow := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		select {
+		case <-time.After(30 * time.Millisecond):
+			return "quote", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	_, stats, err := DoStats(context.Background(), lr, slow)
+	if err != nil || stats.Hedges != 1 || calls.Load() != 2 {
+		t.Fatalf("stats %+v, calls %d, err %v", stats, calls.Load(), err)
+	}
+
+	// the hedge used the only budgeted retry, so the next slow call is not hedged
+	calls.Store(0)
+	_, stats, err = DoStats(context.Background(), lr, slow)
+	if err != nil || stats.Hedges != 0 || calls.Load() != 1 {
+		t.Fatalf("stats %+v, calls %d, err %v", stats, calls.Load(), err)
+	}
+}
@@ -0,0 +1,282 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter 限流器，*rate.Limiter 与 LimiterRegistry 均实现该接口，可通过 Policy.Limiter 用于 LimiterRetry
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Limit 令牌桶的速率与突发容量，零值表示不限流
+type Limit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+func (l Limit) unlimited() bool { return l.Rate == 0 && l.Burst == 0 }
+
+// Scope 限流层级，一次调用需依次通过全局、账户、接口三级令牌桶
+type Scope int
+
+const (
+	ScopeGlobal   Scope = iota // 所有调用共享
+	ScopeAccount               // 每个账户一个
+	ScopeEndpoint              // 每个账户的每个接口一个
+)
+
+const DefaultLimiterIdle = 10 * time.Minute
+
+// RegistryOption 限流器注册表配置，Global/Account/Endpoint 为各层级的默认限制
+type RegistryOption struct {
+	Global   Limit
+	Account  Limit
+	Endpoint Limit
+	Idle     time.Duration // 账户与接口令牌桶闲置超过 Idle 后回收，默认 DefaultLimiterIdle
+	Clock    Clock         // 仅用于闲置回收计时
+}
+
+type bucketKey struct {
+	scope    Scope
+	account  string
+	endpoint string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// LimiterRegistry 按账户与接口分级的限流器注册表
+type LimiterRegistry struct {
+	mu        sync.Mutex
+	defaults  [3]Limit
+	limits    [3]map[string]Limit // 按账户名或接口名覆盖默认限制
+	buckets   map[bucketKey]*bucket
+	idle      time.Duration
+	lastSweep time.Time
+	clock     Clock
+}
+
+// NewLimiterRegistry 创建限流器注册表
+func NewLimiterRegistry(opt RegistryOption) *LimiterRegistry {
+	if opt.Idle <= 0 {
+		opt.Idle = DefaultLimiterIdle
+	}
+	if opt.Clock == nil {
+		opt.Clock = RealClock{}
+	}
+	lr := &LimiterRegistry{
+		defaults: [3]Limit{opt.Global, opt.Account, opt.Endpoint},
+		buckets:  make(map[bucketKey]*bucket),
+		idle:     opt.Idle,
+		clock:    opt.Clock,
+	}
+	for i := range lr.limits {
+		lr.limits[i] = make(map[string]Limit)
+	}
+	lr.lastSweep = lr.clock.Now()
+	return lr
+}
+
+// SetLimit 运行时调整限制，name 为账户名或接口名，为空时调整该层级的默认限制
+// 已创建的令牌桶立即生效
+func (lr *LimiterRegistry) SetLimit(scope Scope, name string, limit Limit) error {
+	if scope < ScopeGlobal || scope > ScopeEndpoint {
+		return fmt.Errorf("invalid limiter scope: %d", scope)
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	if name == "" || scope == ScopeGlobal {
+		lr.defaults[scope] = limit
+	} else {
+		lr.limits[scope][name] = limit
+	}
+	for key, b := range lr.buckets {
+		if key.scope == scope {
+			l := lr.limitOf(key)
+			b.limiter.SetLimit(l.rate())
+			b.limiter.SetBurst(l.burst())
+		}
+	}
+	return nil
+}
+
+// ResetLimit 移除账户或接口的覆盖限制，恢复为层级默认值
+func (lr *LimiterRegistry) ResetLimit(scope Scope, name string) error {
+	if scope < ScopeAccount || scope > ScopeEndpoint {
+		return fmt.Errorf("invalid limiter scope: %d", scope)
+	}
+
+	lr.mu.Lock()
+	delete(lr.limits[scope], name)
+	limit := lr.defaults[scope]
+	lr.mu.Unlock()
+
+	// 重新应用默认值到已创建的令牌桶
+	return lr.SetLimit(scope, "", limit)
+}
+
+func (l Limit) rate() rate.Limit {
+	if l.unlimited() {
+		return rate.Inf
+	}
+	return l.Rate
+}
+
+// burst 未设置突发容量时至少允许一次调用
+func (l Limit) burst() int {
+	if l.Burst <= 0 {
+		return 1
+	}
+	return l.Burst
+}
+
+// limitOf 调用方需持有 mu
+func (lr *LimiterRegistry) limitOf(key bucketKey) Limit {
+	name := key.account
+	if key.scope == ScopeEndpoint {
+		name = key.endpoint
+	}
+	if key.scope != ScopeGlobal {
+		if l, ok := lr.limits[key.scope][name]; ok {
+			return l
+		}
+	}
+	return lr.defaults[key.scope]
+}
+
+// acquire 返回一次调用需通过的令牌桶，调用方需持有 mu
+func (lr *LimiterRegistry) acquire(account, endpoint string, now time.Time) []*rate.Limiter {
+	lr.sweep(now)
+
+	keys := [3]bucketKey{{scope: ScopeGlobal}, {scope: ScopeAccount, account: account}, {scope: ScopeEndpoint, account: account, endpoint: endpoint}}
+	limiters := make([]*rate.Limiter, 0, len(keys))
+	for _, key := range keys {
+		b, ok := lr.buckets[key]
+		if !ok {
+			l := lr.limitOf(key)
+			if l.unlimited() {
+				continue
+			}
+			b = &bucket{limiter: rate.NewLimiter(l.rate(), l.burst())}
+			lr.buckets[key] = b
+		}
+		b.lastUsed = now
+		limiters = append(limiters, b.limiter)
+	}
+	return limiters
+}
+
+// sweep 回收闲置的账户与接口令牌桶，调用方需持有 mu
+func (lr *LimiterRegistry) sweep(now time.Time) {
+	if now.Sub(lr.lastSweep) < lr.idle {
+		return
+	}
+	lr.lastSweep = now
+	for key, b := range lr.buckets {
+		if key.scope != ScopeGlobal && now.Sub(b.lastUsed) >= lr.idle {
+			delete(lr.buckets, key)
+		}
+	}
+}
+
+// Len 当前令牌桶数量
+func (lr *LimiterRegistry) Len() int {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	return len(lr.buckets)
+}
+
+// WaitKey 等待 account/endpoint 的全部层级放行，任一层级无法在 ctx 截止前放行时不消耗任何令牌
+func (lr *LimiterRegistry) WaitKey(ctx context.Context, account, endpoint string) error {
+	lr.mu.Lock()
+	limiters := lr.acquire(account, endpoint, lr.clock.Now())
+	lr.mu.Unlock()
+
+	return waitAll(ctx, limiters)
+}
+
+// Wait 按 WithLimitKey 写入 ctx 的账户与接口等待放行，实现 Limiter
+func (lr *LimiterRegistry) Wait(ctx context.Context) error {
+	key, _ := ctx.Value(limitKeyCtx{}).(limitKey)
+	return lr.WaitKey(ctx, key.account, key.endpoint)
+}
+
+// Key 返回绑定账户与接口的 Limiter
+func (lr *LimiterRegistry) Key(account, endpoint string) Limiter {
+	return keyedLimiter{registry: lr, account: account, endpoint: endpoint}
+}
+
+type keyedLimiter struct {
+	registry          *LimiterRegistry
+	account, endpoint string
+}
+
+func (kl keyedLimiter) Wait(ctx context.Context) error {
+	return kl.registry.WaitKey(ctx, kl.account, kl.endpoint)
+}
+
+type limitKeyCtx struct{}
+
+type limitKey struct {
+	account, endpoint string
+}
+
+// WithLimitKey 在 ctx 中写入本次调用的账户与接口，供 LimiterRegistry.Wait 使用
+func WithLimitKey(ctx context.Context, account, endpoint string) context.Context {
+	return context.WithValue(ctx, limitKeyCtx{}, limitKey{account: account, endpoint: endpoint})
+}
+
+// waitAll 同时预订全部令牌桶，按最长等待时间等待，失败时归还全部预订
+func waitAll(ctx context.Context, limiters []*rate.Limiter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+
+	var delay time.Duration
+	for _, l := range limiters {
+		r := l.ReserveN(now, 1)
+		if !r.OK() {
+			cancel()
+			return fmt.Errorf("rate: wait(n=1) exceeds limiter's burst %d", l.Burst())
+		}
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < delay {
+		cancel()
+		return fmt.Errorf("rate: wait(n=1) would exceed context deadline")
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,183 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultRedisLimiterTimeout  = time.Second
+	DefaultRedisLimiterCooldown = 5 * time.Second
+)
+
+// tokenBucketScript 原子地补充并扣减令牌，使用 Redis 服务器时间避免各进程时钟偏差
+// 返回 {是否放行, 需等待的微秒数}
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local state = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate / 1000000)
+	ts = now
+end
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000000 / rate)
+end
+
+redis.call('HMSET', key, 'tokens', tokens, 'ts', ts)
+redis.call('PEXPIRE', key, math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, wait}
+`)
+
+// RedisLimiterOption Redis 分布式限流配置
+type RedisLimiterOption struct {
+	Key        string          // 令牌桶的 Redis key，同一 key 的各进程共享限制
+	Limit      Limit           // 全部进程合计的限制
+	Fallback   Limit           // Redis 不可用时本进程的限制，必须设置；通常为 Limit 除以进程数，避免故障期间合计超限
+	OnFallback func(err error) // 从 Redis 切换到本地限流时调用，每次故障调用一次
+	Timeout    time.Duration   // 单次脚本调用超时，默认 DefaultRedisLimiterTimeout
+	Cooldown   time.Duration   // 出错后使用本地限流的时长，期间不访问 Redis，到期后由一次调用探测恢复，默认 DefaultRedisLimiterCooldown
+}
+
+// RedisLimiter 基于 Redis 令牌桶的分布式限流器，实现 Limiter
+// client 可使用 redis 包 Impl 返回的客户端
+type RedisLimiter struct {
+	client redis.Scripter
+	opt    RedisLimiterOption
+	local  *rate.Limiter
+
+	mu        sync.Mutex
+	degraded  bool      // 正在使用本地限流
+	downUntil time.Time // 冷却结束时间，之前的调用不访问 Redis
+}
+
+// NewRedisLimiter 创建分布式限流器
+func NewRedisLimiter(client redis.Scripter, opt RedisLimiterOption) (*RedisLimiter, error) {
+	if client == nil || opt.Key == "" {
+		return nil, fmt.Errorf("invalid redis limiter: key=%q", opt.Key)
+	}
+	if opt.Limit.Rate <= 0 || opt.Limit.Rate == rate.Inf {
+		return nil, fmt.Errorf("redis limiter rate must be positive and finite: %v", opt.Limit.Rate)
+	}
+	if opt.Fallback.Rate <= 0 {
+		return nil, fmt.Errorf("redis limiter requires a per-process fallback rate: %v", opt.Fallback.Rate)
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = DefaultRedisLimiterTimeout
+	}
+	if opt.Cooldown <= 0 {
+		opt.Cooldown = DefaultRedisLimiterCooldown
+	}
+	return &RedisLimiter{
+		client: client,
+		opt:    opt,
+		local:  rate.NewLimiter(opt.Fallback.rate(), opt.Fallback.burst()),
+	}, nil
+}
+
+// Allow 尝试取得一个令牌，未放行时返回需等待的时间
+func (rl *RedisLimiter) Allow(ctx context.Context) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, rl.opt.Timeout)
+	defer cancel()
+
+	rateArg := strconv.FormatFloat(float64(rl.opt.Limit.Rate), 'f', -1, 64)
+	res, err := tokenBucketScript.Run(ctx, rl.client, []string{rl.opt.Key}, rateArg, rl.opt.Limit.burst()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected token bucket reply: %v", res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Microsecond, nil
+}
+
+// Wait 等待放行，Redis 不可用时退回本地令牌桶，冷却期内不再访问 Redis
+func (rl *RedisLimiter) Wait(ctx context.Context) error {
+	for {
+		if !rl.probe() {
+			return rl.local.Wait(ctx)
+		}
+		allowed, wait, err := rl.Allow(ctx)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			rl.fallback(err)
+			return rl.local.Wait(ctx)
+		}
+		rl.restore()
+		if allowed {
+			return nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return errors.New("rate: wait(n=1) would exceed context deadline")
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// probe 是否访问 Redis；冷却期内返回 false，冷却结束时只放行一次探测，其余调用继续使用本地限流
+func (rl *RedisLimiter) probe() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if !rl.degraded {
+		return true
+	}
+	now := time.Now()
+	if now.Before(rl.downUntil) {
+		return false
+	}
+	rl.downUntil = now.Add(rl.opt.Cooldown)
+	return true
+}
+
+// fallback 进入或延长冷却期，仅在从 Redis 切换到本地时通知
+func (rl *RedisLimiter) fallback(err error) {
+	rl.mu.Lock()
+	notify := !rl.degraded
+	rl.degraded = true
+	rl.downUntil = time.Now().Add(rl.opt.Cooldown)
+	rl.mu.Unlock()
+
+	if notify && rl.opt.OnFallback != nil {
+		rl.opt.OnFallback(err)
+	}
+}
+
+func (rl *RedisLimiter) restore() {
+	rl.mu.Lock()
+	rl.degraded = false
+	rl.mu.Unlock()
+}
@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// newMiniredis 启动执行真实 Lua 脚本的进程内 Redis，TIME 使用 SetTime 设置的时间
+func newMiniredis(t *testing.T, now time.Time) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+	mr.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), Protocol: 2, DisableIdentity: true, MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+func TestRedisLimiterSharedAcrossProcesses(t *testing.T) {
+	now := time.Now()
+	mr, client := newMiniredis(t, now)
+	opt := RedisLimiterOption{Key: "limit:api-key-1", Limit: Limit{Rate: 10, Burst: 3}, Fallback: Limit{Rate: 5, Burst: 1}}
+	a, err := NewRedisLimiter(client, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewRedisLimiter(client, opt)
+
+	allowed := 0
+	for _, rl := range []*RedisLimiter{a, b, a, b} {
+		ok, wait, err := rl.Allow(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			allowed++
+		} else if wait != 100*time.Millisecond {
+			t.Fatalf("wait %v, want 100ms", wait)
+		}
+	}
+	if allowed != 3 {
+		t.Fatalf("%d calls allowed across processes, want burst 3", allowed)
+	}
+	if tokens, _ := strconv.ParseFloat(mr.HGet(opt.Key, "tokens"), 64); tokens != 0 {
+		t.Fatalf("bucket state tokens=%v, want 0", tokens)
+	}
+	if ttl := mr.TTL(opt.Key); ttl != 1300*time.Millisecond {
+		t.Fatalf("bucket ttl %v, want refill time plus 1s", ttl)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = b.Wait(ctx); err == nil {
+		t.Fatal("expected deadline error")
+	}
+
+	mr.SetTime(now.Add(100 * time.Millisecond))
+	if err = a.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewRedisLimiter(client, RedisLimiterOption{Key: "k", Fallback: opt.Fallback}); err == nil {
+		t.Fatal("expected error for zero rate")
+	}
+	if _, err = NewRedisLimiter(client, RedisLimiterOption{Key: "k", Limit: opt.Limit}); err == nil {
+		t.Fatal("expected error for missing per-process fallback")
+	}
+}
+
+func TestRedisLimiterFallback(t *testing.T) {
+	mr, client := newMiniredis(t, time.Now())
+	key := "limit:api-key-1"
+	mr.Close()
+	var fallbacks int
+	rl, err := NewRedisLimiter(client, RedisLimiterOption{
+		Key:        key,
+		Limit:      Limit{Rate: 100, Burst: 10},
+		Fallback:   Limit{Rate: rate.Inf},
+		OnFallback: func(error) { fallbacks++ },
+		Cooldown:   50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = rl.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fallbacks != 1 {
+		t.Fatalf("fallback reported %d times during one outage", fallbacks)
+	}
+
+	// Redis is back but the limiter stays local until the cooldown ends
+	if err = mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if err = rl.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(key) {
+		t.Fatal("redis probed during cooldown")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err = rl.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists(key) {
+		t.Fatal("redis not probed after cooldown")
+	}
+
+	// a second outage is reported again
+	mr.Close()
+	if err = rl.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fallbacks != 2 {
+		t.Fatalf("fallback reported %d times after two outages", fallbacks)
+	}
+
+	// the local fallback limit is enforced
+	local, _ := NewRedisLimiter(client, RedisLimiterOption{Key: key, Limit: Limit{Rate: 100}, Fallback: Limit{Rate: 1, Burst: 1}})
+	if err = local.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = local.Wait(ctx); err == nil {
+		t.Fatal("local fallback limit not enforced")
+	}
+
+	// as a Policy.Limiter
+	lr := NewLR(Policy{Limiter: rl})
+	if _, err = Do(context.Background(), lr, func(context.Context) (bool, error) { return true, nil }); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func tryWait(registry *LimiterRegistry, account, endpoint string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	return registry.WaitKey(ctx, account, endpoint) == nil
+}
+
+func TestLimiterRegistryHierarchy(t *testing.T) {
+	slow := rate.Every(time.Hour)
+	registry := NewLimiterRegistry(RegistryOption{
+		Global:   Limit{Rate: slow, Burst: 3},
+		Account:  Limit{Rate: slow, Burst: 2},
+		Endpoint: Limit{Rate: slow, Burst: 1},
+	})
+
+	for _, c := range []struct {
+		account, endpoint string
+		ok                bool
+	}{
+		{"a", "orders", true},
+		{"a", "orders", false},    // endpoint bucket empty
+		{"a", "cancel", true},     // separate endpoint bucket
+		{"a", "positions", false}, // account bucket empty
+		{"b", "orders", true},     // rejected calls above did not consume global tokens
+		{"b", "cancel", false},    // global bucket empty
+	} {
+		if got := tryWait(registry, c.account, c.endpoint); got != c.ok {
+			t.Fatalf("%s/%s allowed=%v, want %v", c.account, c.endpoint, got, c.ok)
+		}
+	}
+
+	// limits change at runtime and apply to existing buckets
+	if err := registry.SetLimit(ScopeGlobal, "", Limit{}); err != nil {
+		t.Fatal(err)
+	}
+	if tryWait(registry, "b", "orders") {
+		t.Fatal("endpoint limit not enforced")
+	}
+	if err := registry.SetLimit(ScopeEndpoint, "orders", Limit{Rate: rate.Inf}); err != nil {
+		t.Fatal(err)
+	}
+	if !tryWait(registry, "b", "orders") {
+		t.Fatal("raised endpoint limit not applied")
+	}
+	if err := registry.ResetLimit(ScopeEndpoint, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if tryWait(registry, "c", "orders") && tryWait(registry, "c", "orders") {
+		t.Fatal("endpoint override not reset")
+	}
+	if err := registry.SetLimit(Scope(9), "", Limit{}); err == nil {
+		t.Fatal("expected error for unknown scope")
+	}
+}
+
+func TestLimiterRegistryEviction(t *testing.T) {
+	clock := NewManualClock(time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local))
+	registry := NewLimiterRegistry(RegistryOption{
+		Global:   Limit{Rate: 1000, Burst: 100},
+		Endpoint: Limit{Rate: 1000, Burst: 100},
+		Idle:     time.Minute,
+		Clock:    clock,
+	})
+
+	for _, account := range []string{"a", "b", "c"} {
+		if !tryWait(registry, account, "orders") {
+			t.Fatal("unexpected rejection")
+		}
+	}
+	if n := registry.Len(); n != 4 {
+		t.Fatalf("%d buckets, want 4", n)
+	}
+
+	clock.Advance(30 * time.Second)
+	tryWait(registry, "a", "orders")
+	clock.Advance(40 * time.Second)
+	tryWait(registry, "d", "orders")
+	if n := registry.Len(); n != 3 {
+		t.Fatalf("%d buckets after eviction, want global, a and d", n)
+	}
+}
+
+func TestLimiterRegistryInsideDo(t *testing.T) {
+	registry := NewLimiterRegistry(RegistryOption{Endpoint: Limit{Rate: rate.Every(time.Hour), Burst: 1}})
+	lr := NewLR(Policy{Limiter: registry})
+
+	calls := 0
+	call := func(account string) error {
+		ctx, cancel := context.WithTimeout(WithLimitKey(context.Background(), account, "orders"), 10*time.Millisecond)
+		defer cancel()
+		_, err := Do(ctx, lr, func(context.Context) (int, error) { calls++; return 0, nil })
+		return err
+	}
+	if err := call("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("a"); err == nil {
+		t.Fatal("expected limiter error")
+	}
+	if err := call("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Key("c", "orders").Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("%d calls, want 2", calls)
+	}
+}
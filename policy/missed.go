@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"container/heap"
+	"time"
+)
+
+// MissedPolicy 错过触发时间的事件处理方式
+// 注册时已过期的事件（AddEvent、Reschedule、LoadEvents）与时钟跳变后集中到期的事件均按此处理
+// 周期事件停顿期间错过的各次合并为一次，只有这一次按此处理
+type MissedPolicy int
+
+const (
+	MissedFire            MissedPolicy = iota // 立即补发
+	MissedFireWithinGrace                     // 错过不超过 Grace 的补发，其余跳过
+	MissedSkip                                // 全部跳过
+	MissedCoalesce                            // 同一 key/类型错过多次只触发同批次中最近的一次
+)
+
+const (
+	DefaultMissedTolerance = time.Second
+	DefaultCheckInterval   = time.Second
+)
+
+// MissedOption 错过事件与时钟跳变处理配置
+type MissedOption struct {
+	Policy        MissedPolicy
+	Grace         time.Duration // MissedFireWithinGrace 的补发窗口
+	Tolerance     time.Duration // 触发延迟不超过 Tolerance 视为按时；墙上时间偏离预期超过 Tolerance 视为时钟跳变
+	CheckInterval time.Duration // 事件循环最长休眠时间，保证时钟向前跳变后及时发现到期事件，<= 0 不限制
+}
+
+// DefaultMissedOption 全部立即补发，每秒检查一次时钟跳变
+func DefaultMissedOption() MissedOption {
+	return MissedOption{
+		Policy:        MissedFire,
+		Tolerance:     DefaultMissedTolerance,
+		CheckInterval: DefaultCheckInterval,
+	}
+}
+
+// SetMissedOption 设置错过事件的处理方式
+func (ttm *TimeEvent) SetMissedOption(opt MissedOption) {
+	if opt.Tolerance < 0 {
+		opt.Tolerance = 0
+	}
+
+	ttm.mu.Lock()
+	ttm.missed = opt
+	ttm.mu.Unlock()
+	ttm.wake()
+}
+
+// SetSkipHook 设置被跳过事件的通知，late 为跳过时相对触发时间的延迟
+func (ttm *TimeEvent) SetSkipHook(fn func(e Event, late time.Duration)) {
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.skipHook = fn
+}
+
+// SetClockJumpHook 设置时钟跳变通知，delta 为墙上时间相对预期的偏移，正数为向前跳变
+func (ttm *TimeEvent) SetClockJumpHook(fn func(delta time.Duration)) {
+	ttm.callbackMu.Lock()
+	defer ttm.callbackMu.Unlock()
+	ttm.jumpHook = fn
+}
+
+type coalesceKey struct {
+	key       string
+	eventType int
+}
+
+// settle 按错过策略筛选到期批次，保持触发顺序，调用方需持有 mu
+func (ttm *TimeEvent) settle(batch []*ScheduledEvent, now time.Time) (fire, skipped []*ScheduledEvent) {
+	opt := ttm.missed
+	late := func(event *ScheduledEvent) time.Duration { return now.Sub(event.timestamp) }
+
+	var latest map[coalesceKey]*ScheduledEvent
+	if opt.Policy == MissedCoalesce {
+		latest = make(map[coalesceKey]*ScheduledEvent)
+		for _, event := range batch {
+			k := coalesceKey{event.key, event.eventType}
+			if cur, ok := latest[k]; !ok || !event.timestamp.Before(cur.timestamp) {
+				latest[k] = event
+			}
+		}
+	}
+
+	for _, event := range batch {
+		ok := true
+		if l := late(event); l > opt.Tolerance {
+			switch opt.Policy {
+			case MissedFireWithinGrace:
+				ok = l <= opt.Grace
+			case MissedSkip:
+				ok = false
+			case MissedCoalesce:
+				ok = latest[coalesceKey{event.key, event.eventType}] == event
+			}
+		}
+		if ok {
+			fire = append(fire, event)
+		} else {
+			skipped = append(skipped, event)
+		}
+	}
+	return fire, skipped
+}
+
+// reportSkipped 通知被跳过的事件，调用方不能持有锁
+func (ttm *TimeEvent) reportSkipped(skipped []*ScheduledEvent, now time.Time) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	ttm.callbackMu.RLock()
+	hook := ttm.skipHook
+	payloads := make([]Event, len(skipped))
+	for i, event := range skipped {
+		payloads[i] = ttm.payload(event)
+	}
+	ttm.callbackMu.RUnlock()
+
+	if hook == nil {
+		return
+	}
+	for i, e := range payloads {
+		hook(e, now.Sub(skipped[i].timestamp))
+	}
+}
+
+// realign 时钟回拨后按当前时间重新计算周期事件，避免等待回拨的时长，调用方需持有 mu
+func (ttm *TimeEvent) realign(now time.Time) {
+	for _, event := range ttm.eventQueue {
+		if event.schedule == nil {
+			continue
+		}
+		next := *event
+		if ttm.arm(&next, now.Add(-event.dur), now) && next.timestamp.Before(event.timestamp) {
+			event.eventTime, event.timestamp = next.eventTime, next.timestamp
+		}
+	}
+	heap.Init(&ttm.eventQueue)
+}
@@ -0,0 +1,185 @@
+package policy
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMissedPolicies(t *testing.T) {
+	now := time.Date(2025, 9, 29, 10, 0, 0, 0, time.Local)
+	for _, c := range []struct {
+		name    string
+		policy  MissedPolicy
+		fired   []string
+		skipped int
+	}{
+		{"fire", MissedFire, []string{"rb-2h", "au-20m", "rb-10m", "rb-30s", "rb+1m"}, 0},
+		{"grace", MissedFireWithinGrace, []string{"rb-10m", "rb-30s", "rb+1m"}, 2},
+		{"skip", MissedSkip, []string{"rb+1m"}, 4},
+		{"coalesce", MissedCoalesce, []string{"au-20m", "rb+1m"}, 3},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			clock := NewManualClock(now)
+			ttm := NewTimeEventWithClock(false, clock)
+			opt := DefaultMissedOption()
+			opt.Policy = c.policy
+			opt.Grace = 15 * time.Minute
+			ttm.SetMissedOption(opt)
+
+			var fired []string
+			skipped := 0
+			ttm.SetSkipHook(func(e Event, late time.Duration) {
+				if late <= opt.Tolerance {
+					t.Errorf("on-time event %+v skipped", e)
+				}
+				skipped++
+			})
+			for _, add := range []struct {
+				key    string
+				offset time.Duration
+				name   string
+			}{
+				{"rb", -2 * time.Hour, "rb-2h"},
+				{"rb", -10 * time.Minute, "rb-10m"},
+				{"rb", -30 * time.Second, "rb-30s"},
+				{"au", -20 * time.Minute, "au-20m"},
+				{"rb", time.Minute, "rb+1m"},
+			} {
+				name := add.name
+				if _, err := ttm.Subscribe(add.key, EventOpenAllow, func(e Event) {
+					if e.EventTime.Equal(now.Add(add.offset)) {
+						fired = append(fired, name)
+					}
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if err := ttm.AddEvent(add.key, EventOpenAllow, now.Add(add.offset), 0, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			clock.Advance(time.Minute)
+			ttm.CleanExpiredEvents()
+			if len(fired) != len(c.fired) || skipped != c.skipped {
+				t.Fatalf("fired %v skipped %d, want %v skipped %d", fired, skipped, c.fired, c.skipped)
+			}
+			for i := range fired {
+				if fired[i] != c.fired[i] {
+					t.Fatalf("fired %v, want %v", fired, c.fired)
+				}
+			}
+		})
+	}
+}
+
+// waitTimer 等待事件循环设置好定时器
+func waitTimer(t *testing.T, clock *ManualClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		clock.mu.Lock()
+		n := len(clock.timers)
+		clock.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("event loop did not arm its timer")
+}
+
+func TestClockJumpDetection(t *testing.T) {
+	start := time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+	opt := DefaultMissedOption()
+	opt.Policy = MissedCoalesce
+	ttm.SetMissedOption(opt)
+
+	jumps := make(chan time.Duration, 4)
+	ttm.SetClockJumpHook(func(delta time.Duration) { jumps <- delta })
+	var (
+		mu      sync.Mutex
+		skipped int
+	)
+	ttm.SetSkipHook(func(Event, time.Duration) {
+		mu.Lock()
+		skipped++
+		mu.Unlock()
+	})
+
+	fired := make(chan Event, 4)
+	heartbeat, err := ttm.RegisterEventType("Heartbeat", OffsetAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []struct {
+		key       string
+		eventType int
+	}{{"hb", heartbeat}, {"rb", EventForceClose}} {
+		if _, err = ttm.Subscribe(sub.key, sub.eventType, func(e Event) { fired <- e }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ttm.AddRecurring("hb", heartbeat, EverySchedule(time.Minute), 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = ttm.AddEvent("rb", EventForceClose, start.Add(30*time.Minute), 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ttm.Start()
+	defer ttm.Stop()
+	waitTimer(t, clock)
+
+	// the VM is suspended for an hour: wall time moves, the monotonic timer does not
+	clock.Jump(time.Hour)
+	clock.Advance(time.Second)
+	select {
+	case delta := <-jumps:
+		if delta != time.Hour {
+			t.Fatalf("forward jump %v, want 1h", delta)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("forward jump not detected")
+	}
+	got := map[string]int{}
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-fired:
+			got[e.Key]++
+		case <-time.After(time.Second):
+			t.Fatalf("missed events not fired, got %v", got)
+		}
+	}
+	mu.Lock()
+	// the heartbeat runs missed during the suspension are merged into one
+	if got["hb"] != 1 || got["rb"] != 1 || skipped != 0 {
+		t.Fatalf("fired %v skipped %d, want one each and none skipped", got, skipped)
+	}
+	mu.Unlock()
+
+	// NTP steps the clock back two hours: the heartbeat is re-armed from the new time
+	clock.Jump(-2 * time.Hour)
+	clock.Advance(time.Second)
+	select {
+	case delta := <-jumps:
+		if delta != -2*time.Hour {
+			t.Fatalf("backward jump %v, want -2h", delta)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("backward jump not detected")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		next, ok := ttm.NextEvent()
+		if ok && next.Key == "hb" && next.TriggerTime.Sub(clock.Now()) <= time.Minute {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("heartbeat not re-armed after clock step back: next %+v at %s", next, clock.Now())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Observer 观察 LimiterRetry 的限流与重试决策，在调用方 goroutine 中同步调用，需尽快返回
+type Observer interface {
+	OnWait(ctx context.Context, wait time.Duration, err error)                    // 每次尝试前等待限流与并发许可后调用
+	OnAttempt(ctx context.Context, attempt int, latency time.Duration, err error) // 每次执行目标函数后调用
+	OnRetry(ctx context.Context, attempt int, backoff time.Duration, err error)   // 决定重试时调用，backoff 后发起第 attempt+1 次尝试
+	OnGiveUp(ctx context.Context, attempts int, err error)                        // 调用以错误结束时调用，err 为返回给调用方的错误
+}
+
+type nopObserver struct{}
+
+func (nopObserver) OnWait(context.Context, time.Duration, error)         {}
+func (nopObserver) OnAttempt(context.Context, int, time.Duration, error) {}
+func (nopObserver) OnRetry(context.Context, int, time.Duration, error)   {}
+func (nopObserver) OnGiveUp(context.Context, int, error)                 {}
+
+// Observers 组合多个 Observer，按顺序调用
+func Observers(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) OnWait(ctx context.Context, wait time.Duration, err error) {
+	for _, o := range m {
+		o.OnWait(ctx, wait, err)
+	}
+}
+
+func (m multiObserver) OnAttempt(ctx context.Context, attempt int, latency time.Duration, err error) {
+	for _, o := range m {
+		o.OnAttempt(ctx, attempt, latency, err)
+	}
+}
+
+func (m multiObserver) OnRetry(ctx context.Context, attempt int, backoff time.Duration, err error) {
+	for _, o := range m {
+		o.OnRetry(ctx, attempt, backoff, err)
+	}
+}
+
+func (m multiObserver) OnGiveUp(ctx context.Context, attempts int, err error) {
+	for _, o := range m {
+		o.OnGiveUp(ctx, attempts, err)
+	}
+}
+
+// ZapObserver 以 zap 记录限流与重试决策，等待与尝试为 Debug，重试为 Warn，放弃为 Error
+type ZapObserver struct {
+	logger *zap.Logger
+}
+
+// NewZapObserver 创建日志观察者，可用 logger.Named 区分不同的 LimiterRetry
+func NewZapObserver(logger *zap.Logger) *ZapObserver {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ZapObserver{logger: logger}
+}
+
+func (zo *ZapObserver) OnWait(_ context.Context, wait time.Duration, err error) {
+	if err != nil {
+		zo.logger.Warn("limiter wait failed", zap.Duration("wait", wait), zap.Error(err))
+		return
+	}
+	zo.logger.Debug("limiter wait", zap.Duration("wait", wait))
+}
+
+func (zo *ZapObserver) OnAttempt(_ context.Context, attempt int, latency time.Duration, err error) {
+	zo.logger.Debug("attempt", zap.Int("attempt", attempt), zap.Duration("latency", latency), zap.Error(err))
+}
+
+func (zo *ZapObserver) OnRetry(_ context.Context, attempt int, backoff time.Duration, err error) {
+	zo.logger.Warn("retry", zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+}
+
+func (zo *ZapObserver) OnGiveUp(_ context.Context, attempts int, err error) {
+	zo.logger.Error("give up", zap.Int("attempts", attempts), zap.Error(err))
+}
+
+// CounterSnapshot 计数快照
+type CounterSnapshot struct {
+	Waits        int64         // 等待次数
+	WaitFailures int64         // 等待失败次数
+	WaitTime     time.Duration // 等待总时长
+	Attempts     int64         // 执行次数
+	Failures     int64         // 执行失败次数
+	Retries      int64         // 重试次数
+	GiveUps      int64         // 以错误结束的调用数
+}
+
+// Counters 累计限流与重试计数的 Observer，可并发使用
+type Counters struct {
+	waits, waitFailures, waitTime atomic.Int64
+	attempts, failures            atomic.Int64
+	retries, giveUps              atomic.Int64
+}
+
+func (c *Counters) OnWait(_ context.Context, wait time.Duration, err error) {
+	c.waits.Add(1)
+	c.waitTime.Add(int64(wait))
+	if err != nil {
+		c.waitFailures.Add(1)
+	}
+}
+
+func (c *Counters) OnAttempt(_ context.Context, _ int, _ time.Duration, err error) {
+	c.attempts.Add(1)
+	if err != nil {
+		c.failures.Add(1)
+	}
+}
+
+func (c *Counters) OnRetry(context.Context, int, time.Duration, error) { c.retries.Add(1) }
+
+func (c *Counters) OnGiveUp(context.Context, int, error) { c.giveUps.Add(1) }
+
+// Snapshot 当前计数
+func (c *Counters) Snapshot() CounterSnapshot {
+	return CounterSnapshot{
+		Waits:        c.waits.Load(),
+		WaitFailures: c.waitFailures.Load(),
+		WaitTime:     time.Duration(c.waitTime.Load()),
+		Attempts:     c.attempts.Load(),
+		Failures:     c.failures.Load(),
+		Retries:      c.retries.Load(),
+		GiveUps:      c.giveUps.Load(),
+	}
+}
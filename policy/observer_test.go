@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/time/rate"
+)
+
+func TestObserverHooks(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	counters := &Counters{}
+	lr := NewLR(Policy{
+		RateLimit:       rate.Inf,
+		MaxRetries:      2,
+		InitialInterval: time.Millisecond,
+		Observer:        Observers(NewZapObserver(zap.New(core).Named("okx")), counters),
+	})
+
+	calls := 0
+	if _, err := Do(context.Background(), lr, func(context.Context) (int, error) {
+		if calls++; calls < 2 {
+			return 0, errors.New("timeout")
+		}
+		return 1, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got := counters.Snapshot()
+	if got.Waits != 2 || got.Attempts != 2 || got.Failures != 1 || got.Retries != 1 || got.GiveUps != 0 {
+		t.Fatalf("unexpected counters after recovery: %+v", got)
+	}
+
+	down := errors.New("down")
+	_, err := Do(context.Background(), lr, func(context.Context) (int, error) { return 0, down })
+	if !errors.Is(err, ErrMaxRetries) || !errors.Is(err, down) {
+		t.Fatalf("expected max retries wrapping the last error, got %v", err)
+	}
+	got = counters.Snapshot()
+	if got.Attempts != 5 || got.Retries != 3 || got.GiveUps != 1 {
+		t.Fatalf("unexpected counters after giving up: %+v", got)
+	}
+
+	if n := logs.FilterMessage("retry").Len(); n != 3 {
+		t.Fatalf("%d retry logs, want 3", n)
+	}
+	giveUps := logs.FilterMessage("give up").All()
+	if len(giveUps) != 1 || giveUps[0].LoggerName != "okx" || giveUps[0].ContextMap()["attempts"] != int64(3) {
+		t.Fatalf("unexpected give up logs: %+v", giveUps)
+	}
+}
+
+func TestRateLimitWaitError(t *testing.T) {
+	counters := &Counters{}
+	lr := NewLR(Policy{RateLimit: rate.Every(time.Hour), Burst: 1, Observer: counters})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := Do(ctx, lr, func(context.Context) (int, error) { return 1, nil }); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Do(ctx, lr, func(context.Context) (int, error) { return 1, nil })
+	if !errors.Is(err, ErrRateLimitWait) {
+		t.Fatalf("expected rate limit wait error, got %v", err)
+	}
+	if got := counters.Snapshot(); got.WaitFailures != 1 || got.Attempts != 1 || got.GiveUps != 1 {
+		t.Fatalf("unexpected counters: %+v", got)
+	}
+}
@@ -2,11 +2,18 @@ package policy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"golang.org/x/time/rate"
 	"time"
 )
 
+var (
+	ErrRateLimitWait = errors.New("rate limit wait failed")
+	ErrBulkheadWait  = errors.New("bulkhead wait failed")
+	ErrMaxRetries    = errors.New("max retries exceeded")
+)
+
 // RetryableFunc 定义支持任意参数的函数类型
 type RetryableFunc func(args ...any) (any, error)
 
@@ -18,7 +25,14 @@ type Policy struct {
 	InitialInterval time.Duration    // 初始重试间隔
 	MaxInterval     time.Duration    // 最大重试间隔
 	Multiplier      float64          // 间隔时间乘数
+	Backoff         BackoffStrategy  // 退避策略，默认指数退避
 	IsRetriable     func(error) bool // 判断错误是否可重试
+	Breaker         *Breaker         // 熔断器，熔断期间不再尝试，直接返回 ErrBreakerOpen
+	Limiter         Limiter          // 自定义限流器（如 LimiterRegistry），设置后忽略 RateLimit 与 Burst
+	Bulkhead        *Bulkhead        // 并发隔离，每次尝试在限流放行后取得许可
+	Budget          *RetryBudget     // 重试预算，耗尽后不再重试，返回 ErrRetryBudgetExhausted
+	Hedge           *Hedge           // 对冲请求，对冲请求需经过重试预算与限流，与首次请求共用熔断与并发许可
+	Observer        Observer         // 观察等待、尝试、重试与放弃，如 ZapObserver、Counters
 }
 
 // DefaultPolicy 默认策略配置
@@ -35,7 +49,7 @@ var DefaultPolicy = Policy{
 // LimiterRetry 限流重试执行器
 type LimiterRetry struct {
 	policy  Policy
-	limiter *rate.Limiter
+	limiter Limiter
 }
 
 // NewLR 创建限流重试执行器
@@ -62,62 +76,166 @@ func NewLR(policy Policy) *LimiterRetry {
 	if policy.IsRetriable == nil {
 		policy.IsRetriable = DefaultPolicy.IsRetriable
 	}
+	if policy.Observer == nil {
+		policy.Observer = nopObserver{}
+	}
 
+	var limiter Limiter = rate.NewLimiter(policy.RateLimit, policy.Burst)
+	if policy.Limiter != nil {
+		limiter = policy.Limiter
+	}
 	return &LimiterRetry{
 		policy:  policy,
-		limiter: rate.NewLimiter(policy.RateLimit, policy.Burst),
+		limiter: limiter,
 	}
 }
 
-// Execute 执行带限流和重试的函数
-func (lr *LimiterRetry) Execute(ctx context.Context, fn RetryableFunc, args ...any) (any, error) {
-	var lastResult interface{}
-	var lastError error
+// RetryStats 一次调用的尝试统计
+type RetryStats struct {
+	Attempts int           // 实际执行次数（含首次）
+	Wait     time.Duration // 限流等待与退避等待的总时长
+	Hedges   int           // 发出的对冲请求数
+}
 
-	// 计算退避时间（修复幂运算的类型问题）
-	calculateBackoff := func(attempt int) time.Duration {
-		if attempt <= 0 {
-			return 0
-		}
-		// 使用整数计算幂次，避免 float64 的 % 运算错误
-		backoff := float64(lr.policy.InitialInterval)
-		for i := 1; i < attempt; i++ {
-			backoff *= lr.policy.Multiplier
-			if backoff > float64(lr.policy.MaxInterval) {
-				return lr.policy.MaxInterval
-			}
-		}
-		return time.Duration(backoff)
+// Do 执行带限流和重试的函数，每次尝试都传入 ctx
+func Do[T any](ctx context.Context, lr *LimiterRetry, fn func(ctx context.Context) (T, error)) (T, error) {
+	result, _, err := DoStats(ctx, lr, fn)
+	return result, err
+}
+
+// DoStats 与 Do 相同，并返回尝试次数与等待时长
+func DoStats[T any](ctx context.Context, lr *LimiterRetry, fn func(ctx context.Context) (T, error)) (T, RetryStats, error) {
+	var (
+		zero       T
+		stats      RetryStats
+		lastResult T
+		lastError  error
+		backoff    time.Duration
+	)
+
+	observer := lr.policy.Observer
+	giveUp := func(err error) error {
+		observer.OnGiveUp(ctx, stats.Attempts, err)
+		return err
 	}
 
 	totalAttempts := lr.policy.MaxRetries + 1 // 总尝试次数（含首次）
 
 	for attempt := 1; attempt <= totalAttempts; attempt++ {
-		// 等待限流许可
-		if err := lr.limiter.Wait(ctx); err != nil {
-			return nil, fmt.Errorf("限流等待失败: %w", err)
+		// 熔断检查在限流之前，避免熔断期间消耗令牌
+		breaker := lr.policy.Breaker
+		var generation uint64
+		if breaker != nil {
+			var err error
+			if generation, err = breaker.allow(); err != nil {
+				if lastError != nil {
+					err = fmt.Errorf("%w, last error: %w", err, lastError)
+				}
+				return lastResult, stats, giveUp(err)
+			}
+		}
+
+		// 等待限流与并发许可
+		release, wait, err := lr.acquire(ctx)
+		stats.Wait += wait
+		observer.OnWait(ctx, wait, err)
+		if err != nil {
+			if breaker != nil {
+				breaker.abort(generation) // 未发出请求，不计入熔断统计
+			}
+			return zero, stats, giveUp(err)
 		}
 
 		// 执行目标函数
-		lastResult, lastError = fn(args...)
+		stats.Attempts = attempt
+		call := fn
+		if hedge := lr.policy.Hedge; hedge != nil {
+			call = func(ctx context.Context) (T, error) {
+				result, hedged, err := hedgeDo(ctx, hedge, fn, lr.hedgeGate)
+				if hedged {
+					stats.Hedges++
+				}
+				return result, err
+			}
+		}
+		start := time.Now()
+		var result T
+		if release != nil {
+			result, err = withTimeout(ctx, lr.policy.Bulkhead.opt.Timeout, call)
+			release(err)
+		} else {
+			result, err = call(ctx)
+		}
+		observer.OnAttempt(ctx, attempt, time.Since(start), err)
+		lastResult, lastError = result, err
+		if breaker != nil {
+			breaker.done(generation, breaker.opt.IsFailure(err))
+		}
+		if err == nil && lr.policy.Budget != nil {
+			lr.policy.Budget.success()
+		}
 
 		// 成功或遇到不可重试的错误，直接返回
-		if lastError == nil || !lr.policy.IsRetriable(lastError) {
-			return lastResult, lastError
+		if err == nil {
+			return result, stats, nil
+		}
+		if !lr.policy.IsRetriable(err) {
+			return result, stats, giveUp(err)
 		}
 
 		// 未达最大尝试次数，等待退避后重试
 		if attempt < totalAttempts {
-			backoff := calculateBackoff(attempt)
+			if budget := lr.policy.Budget; budget != nil && !budget.withdraw() {
+				return lastResult, stats, giveUp(fmt.Errorf("%w, last error: %w", ErrRetryBudgetExhausted, lastError))
+			}
+
+			// 服务端建议的等待时间优先于计算出的退避时间
+			if after, ok := RetryAfterOf(err); ok {
+				backoff = after
+			} else {
+				backoff = lr.policy.delay(attempt, backoff)
+			}
+			observer.OnRetry(ctx, attempt, backoff, err)
+			timer := time.NewTimer(backoff)
 			select {
 			case <-ctx.Done():
-				return nil, fmt.Errorf("上下文已结束: %w", ctx.Err())
-			case <-time.After(backoff):
-				continue // 继续重试
+				timer.Stop()
+				return zero, stats, giveUp(fmt.Errorf("retry backoff interrupted: %w", ctx.Err()))
+			case <-timer.C:
+				stats.Wait += backoff
 			}
 		}
 	}
 
 	// 达到最大重试次数
-	return lastResult, fmt.Errorf("达到最大重试次数（%d次），最后错误: %w", lr.policy.MaxRetries, lastError)
+	return lastResult, stats, giveUp(fmt.Errorf("%w (%d), last error: %w", ErrMaxRetries, lr.policy.MaxRetries, lastError))
+}
+
+// acquire 等待限流放行并取得并发许可，release 为 nil 表示未配置并发隔离
+func (lr *LimiterRetry) acquire(ctx context.Context) (release func(error), wait time.Duration, err error) {
+	start := time.Now()
+	defer func() { wait = time.Since(start) }()
+
+	if err := lr.limiter.Wait(ctx); err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrRateLimitWait, err)
+	}
+	if bulkhead := lr.policy.Bulkhead; bulkhead != nil {
+		if release, err = bulkhead.Acquire(ctx); err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", ErrBulkheadWait, err)
+		}
+	}
+	return release, 0, nil
+}
+
+// hedgeGate 对冲请求发出前扣减重试预算并等待限流
+func (lr *LimiterRetry) hedgeGate(ctx context.Context) error {
+	if budget := lr.policy.Budget; budget != nil && !budget.withdraw() {
+		return ErrRetryBudgetExhausted
+	}
+	return lr.limiter.Wait(ctx)
+}
+
+// Execute 执行带限流和重试的函数，兼容旧接口，新代码请使用 Do
+func (lr *LimiterRetry) Execute(ctx context.Context, fn RetryableFunc, args ...any) (any, error) {
+	return Do(ctx, lr, func(context.Context) (any, error) { return fn(args...) })
 }
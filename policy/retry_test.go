@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestDoRetriesWithContext(t *testing.T) {
+	lr := NewLR(Policy{
+		RateLimit:       rate.Inf,
+		MaxRetries:      3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     2 * time.Millisecond,
+	})
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "req-1")
+	calls := 0
+	price, stats, err := DoStats(ctx, lr, func(ctx context.Context) (float64, error) {
+		if ctx.Value(key{}) != "req-1" {
+			t.Fatal("attempt did not receive the caller context")
+		}
+		calls++
+		if calls < 3 {
+			return 0, errors.New("temporary")
+		}
+		return 3650.5, nil
+	})
+	if err != nil || price != 3650.5 {
+		t.Fatalf("got %v, %v", price, err)
+	}
+	if stats.Attempts != 3 || stats.Wait < 3*time.Millisecond {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	permanent := errors.New("permanent")
+	lr = NewLR(Policy{RateLimit: rate.Inf, IsRetriable: func(err error) bool { return !errors.Is(err, permanent) }})
+	_, stats, err = DoStats(ctx, lr, func(context.Context) (int, error) { return 0, permanent })
+	if !errors.Is(err, permanent) || stats.Attempts != 1 {
+		t.Fatalf("non-retriable error retried: %+v %v", stats, err)
+	}
+}
+
+func TestExecuteCompatibility(t *testing.T) {
+	lr := NewLR(Policy{RateLimit: rate.Inf, MaxRetries: 1, InitialInterval: time.Millisecond})
+	result, err := lr.Execute(context.Background(), func(args ...any) (any, error) {
+		return args[0].(int) + args[1].(int), nil
+	}, 1, 2)
+	if err != nil || result.(int) != 3 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+
+	failure := errors.New("down")
+	result, err = lr.Execute(context.Background(), func(...any) (any, error) { return "last", failure })
+	if !errors.Is(err, failure) || result != "last" {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestBackoffStrategies(t *testing.T) {
+	base := Policy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+	within := func(d, lower, upper time.Duration) bool { return d >= lower && d <= upper }
+
+	for attempt := 1; attempt <= 6; attempt++ {
+		exp := base.exponential(attempt)
+		for i := 0; i < 50; i++ {
+			p := base
+			p.Backoff = BackoffFullJitter
+			if d := p.delay(attempt, 0); !within(d, 0, exp) {
+				t.Fatalf("full jitter attempt %d: %v not in [0, %v]", attempt, d, exp)
+			}
+			p.Backoff = BackoffEqualJitter
+			if d := p.delay(attempt, 0); !within(d, exp/2, exp) {
+				t.Fatalf("equal jitter attempt %d: %v not in [%v, %v]", attempt, d, exp/2, exp)
+			}
+			p.Backoff = BackoffDecorrelated
+			if d := p.delay(attempt, 400*time.Millisecond); !within(d, base.InitialInterval, base.MaxInterval) {
+				t.Fatalf("decorrelated attempt %d: %v out of bounds", attempt, d)
+			}
+		}
+	}
+
+	p := base
+	if d := p.delay(5, 0); d != time.Second {
+		t.Fatalf("exponential capped at %v", d)
+	}
+	p.Backoff = BackoffConstant
+	if d := p.delay(5, 0); d != 100*time.Millisecond {
+		t.Fatalf("constant %v", d)
+	}
+	p.Backoff = BackoffLinear
+	if d := p.delay(3, 0); d != 300*time.Millisecond {
+		t.Fatalf("linear %v", d)
+	}
+	if d := p.delay(30, 0); d != time.Second {
+		t.Fatalf("linear capped at %v", d)
+	}
+}
+
+func TestRetryAfterOverridesBackoff(t *testing.T) {
+	lr := NewLR(Policy{RateLimit: rate.Inf, MaxRetries: 1, InitialInterval: time.Hour, MaxInterval: time.Hour})
+	throttled := errors.New("429 Too Many Requests")
+
+	calls := 0
+	_, stats, err := DoStats(context.Background(), lr, func(context.Context) (string, error) {
+		if calls++; calls == 1 {
+			return "", fmt.Errorf("query orders: %w", WithRetryAfter(throttled, 20*time.Millisecond))
+		}
+		return "ok", nil
+	})
+	if err != nil || stats.Attempts != 2 || stats.Wait < 20*time.Millisecond || stats.Wait > time.Second {
+		t.Fatalf("unexpected result %+v %v", stats, err)
+	}
+
+	now := time.Date(2025, 9, 29, 9, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"Mon, 29 Sep 2025 09:00:30 GMT": 30 * time.Second,
+	} {
+		if d, ok := ParseRetryAfter(value, now); !ok || d != want {
+			t.Fatalf("ParseRetryAfter(%q) = %v, %v", value, d, ok)
+		}
+	}
+	if _, ok := ParseRetryAfter("soon", now); ok {
+		t.Fatal("invalid Retry-After accepted")
+	}
+}
@@ -0,0 +1,155 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxRearmSteps 周期事件追赶过期触发点的最大步数
+const maxRearmSteps = 100000
+
+// Schedule 周期调度规则，Next 返回 t 之后的下一次事件时间，零值表示不再触发
+// robfig/cron 的 cron.Schedule 可直接使用
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// CronSchedule 解析 cron 表达式，秒字段可选，支持 @every、@daily 等描述符
+func CronSchedule(spec string) (Schedule, error) {
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+// EverySchedule 固定间隔
+func EverySchedule(interval time.Duration) Schedule {
+	return everySchedule(interval)
+}
+
+type tradingDaySchedule struct {
+	cal *Calendar
+	at  time.Duration
+}
+
+func (s tradingDaySchedule) Next(t time.Time) time.Time {
+	day := s.cal.dayOf(t)
+	if s.cal.IsTradingDay(day) {
+		if next := s.cal.at(day, s.at); next.After(t) {
+			return next
+		}
+	}
+	return s.cal.at(s.cal.NextTradingDay(day), s.at)
+}
+
+// Daily 每个交易日日历时区的 at 时刻（距当地零点偏移），配合事件类型的偏移可表达“每个交易日 15:00 前 5 分钟”
+func (cal *Calendar) Daily(at time.Duration) Schedule {
+	return tradingDaySchedule{cal: cal, at: at}
+}
+
+// AddRecurring 添加周期事件，触发后按 schedule 自动重新入队，规则按 SetLocation 设置的 key 时区求值
+// 触发时间 = 事件类型的偏移(事件时间, dur)，与队列中已有事件重复时忽略，周期事件不参与 SaveEvents 持久化
+func (ttm *TimeEvent) AddRecurring(key string, eventType int, schedule Schedule, dur time.Duration, cb func(int)) error {
+	if schedule == nil {
+		return fmt.Errorf("schedule cannot be nil: key=%s", key)
+	}
+	if interval, ok := schedule.(everySchedule); ok && interval <= 0 {
+		return fmt.Errorf("interval must be positive: %v", time.Duration(interval))
+	}
+
+	ttm.callbackMu.RLock()
+	_, ok := ttm.eventTypes[eventType]
+	ttm.callbackMu.RUnlock()
+	if key == "" || !ok {
+		return fmt.Errorf("invalid key or event type: key=%s, eventType=%d", key, eventType)
+	}
+	if dur < 0 {
+		return fmt.Errorf("duration cannot be negative: %v", dur)
+	}
+
+	ttm.mu.Lock()
+	defer ttm.mu.Unlock()
+
+	if len(ttm.eventQueue) >= ttm.maxQueueSize {
+		return fmt.Errorf("event queue size limit reached: %d", ttm.maxQueueSize)
+	}
+
+	now := ttm.clock.Now()
+	event := &ScheduledEvent{
+		key:       key,
+		eventType: eventType,
+		schedule:  schedule,
+		dur:       dur,
+		fn:        cb,
+	}
+	if !ttm.arm(event, now.Add(-dur), now) {
+		return fmt.Errorf("schedule has no future occurrence: key=%s", key)
+	}
+	if ttm.hasEvent(key, eventType, event.eventTime) {
+		return nil // 事件已存在，忽略
+	}
+	ttm.push(event)
+	ttm.wake()
+	return nil
+}
+
+// rearm 周期事件触发后计算下一次并入队，调用方需持有 mu
+// 恰好在 now 到期的下一次会在同一批次中触发；停顿期间错过的各次合并到本次触发，从 now 起寻找下一次
+func (ttm *TimeEvent) rearm(fired *ScheduledEvent, now time.Time) {
+	next := &ScheduledEvent{
+		key:       fired.key,
+		eventType: fired.eventType,
+		callback:  fired.callback,
+		fn:        fired.fn,
+		schedule:  fired.schedule,
+		dur:       fired.dur,
+	}
+	if !ttm.arm(next, fired.eventTime, fired.timestamp) {
+		return
+	}
+	if next.timestamp.Before(now) && !ttm.arm(next, laterOf(fired.eventTime, now.Add(-next.dur)), now) {
+		return
+	}
+	if !ttm.hasEvent(next.key, next.eventType, next.eventTime) {
+		ttm.push(next)
+	}
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// arm 从 from 起寻找触发时间晚于 after 的下一次事件时间，调用方需持有 mu
+func (ttm *TimeEvent) arm(event *ScheduledEvent, from, after time.Time) bool {
+	ttm.callbackMu.RLock()
+	offset := ttm.eventTypes[event.eventType].offset
+	loc := ttm.location(event.key)
+	ttm.callbackMu.RUnlock()
+
+	// cron 规则按传入时间的时区求值，转换到 key 的时区后夏令时切换日仍对应当地时刻
+	eventTime := from.In(loc)
+	for i := 0; i < maxRearmSteps; i++ {
+		eventTime = event.schedule.Next(eventTime)
+		if eventTime.IsZero() {
+			return false
+		}
+		if trigger := offset(eventTime, event.dur); trigger.After(after) {
+			event.eventTime = eventTime
+			event.timestamp = trigger
+			return true
+		}
+	}
+	return false
+}
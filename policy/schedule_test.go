@@ -0,0 +1,244 @@
+package policy
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecurringEvents(t *testing.T) {
+	friday := time.Date(2025, 9, 26, 14, 0, 0, 0, time.Local)
+	clock := NewManualClock(friday)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	var fired []Event
+	collect := func(e Event) { fired = append(fired, e) }
+
+	snapshot, err := ttm.RegisterEventType("Snapshot", OffsetAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cronSchedule, err := CronSchedule("0 */30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CronSchedule("not a spec"); err == nil {
+		t.Fatal("expected error for invalid cron spec")
+	}
+
+	cal := shfeCalendar()
+	for _, add := range []struct {
+		key       string
+		eventType int
+		schedule  Schedule
+		dur       time.Duration
+	}{
+		{"snapshot", snapshot, cronSchedule, 0},
+		{"heartbeat", snapshot, EverySchedule(45 * time.Minute), 0},
+		{"rb", EventForceClose, cal.Daily(15 * time.Hour), 5 * time.Minute},
+	} {
+		if _, err = ttm.Subscribe(add.key, add.eventType, collect); err != nil {
+			t.Fatal(err)
+		}
+		if err = ttm.AddRecurring(add.key, add.eventType, add.schedule, add.dur, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ttm.AddRecurring("bad", snapshot, EverySchedule(0), 0, nil); err == nil {
+		t.Fatal("expected error for zero interval")
+	}
+
+	if pending := ttm.Pending(); len(pending) != 3 || !pending[0].Recurring {
+		t.Fatalf("unexpected pending %+v", pending)
+	}
+
+	clock.Set(friday.Add(time.Hour))
+	ttm.CleanExpiredEvents()
+
+	count := func(key string) int {
+		n := 0
+		for _, e := range fired {
+			if e.Key == key {
+				n++
+			}
+		}
+		return n
+	}
+	// 14:30 and 15:00 snapshots, 14:45 heartbeat, 14:55 force close
+	if count("snapshot") != 2 || count("heartbeat") != 1 || count("rb") != 1 {
+		t.Fatalf("unexpected fired events %+v", fired)
+	}
+
+	// every recurring event re-armed itself, the force close on the next trading day
+	for _, e := range ttm.Pending() {
+		if e.Key == "rb" && !e.TriggerTime.Equal(time.Date(2025, 9, 29, 14, 55, 0, 0, time.Local)) {
+			t.Fatalf("force close re-armed at %s", e.TriggerTime)
+		}
+	}
+	if len(ttm.Pending()) != 3 {
+		t.Fatalf("pending %d after firing, want 3", len(ttm.Pending()))
+	}
+
+	if n := ttm.RemoveEvent("snapshot", snapshot); n != 1 {
+		t.Fatalf("removed %d", n)
+	}
+	fired = nil
+	clock.Set(friday.Add(3 * time.Hour))
+	ttm.CleanExpiredEvents()
+	if count("snapshot") != 0 || count("heartbeat") == 0 {
+		t.Fatalf("unexpected fired events after removal %+v", fired)
+	}
+}
+
+func TestRescheduleRecurringKeepsDur(t *testing.T) {
+	friday := time.Date(2025, 9, 26, 14, 0, 0, 0, time.Local)
+	clock := NewManualClock(friday)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	if err := ttm.AddRecurring("rb", EventForceClose, shfeCalendar().Daily(15*time.Hour), 5*time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	closeTime := friday.Add(time.Hour)
+	if err := ttm.Reschedule("rb", EventForceClose, closeTime, closeTime, 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if pending := ttm.Pending(); !pending[0].TriggerTime.Equal(closeTime.Add(-10 * time.Minute)) {
+		t.Fatalf("rescheduled trigger %s", pending[0].TriggerTime)
+	}
+
+	clock.Set(closeTime)
+	ttm.CleanExpiredEvents()
+	// the next trading day keeps the rescheduled lead time
+	if pending := ttm.Pending(); len(pending) != 1 || !pending[0].TriggerTime.Equal(time.Date(2025, 9, 29, 14, 50, 0, 0, time.Local)) {
+		t.Fatalf("unexpected pending %+v", pending)
+	}
+}
+
+func TestAddRecurringIgnoresDuplicate(t *testing.T) {
+	start := time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	fired := 0
+	for i := 0; i < 2; i++ {
+		if err := ttm.AddRecurring("tick", EventOpenAllow, EverySchedule(time.Minute), 0, func(int) { fired++ }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(ttm.Pending()); n != 1 {
+		t.Fatalf("pending %d after duplicate AddRecurring, want 1", n)
+	}
+	clock.Advance(time.Minute)
+	ttm.CleanExpiredEvents()
+	if fired != 1 {
+		t.Fatalf("fired %d on first tick, want 1", fired)
+	}
+}
+
+func TestRecurringMergesMissedRuns(t *testing.T) {
+	start := time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+
+	fired := 0
+	if err := ttm.AddRecurring("tick", EventOpenAllow, EverySchedule(time.Second), 0, func(int) { fired++ }); err != nil {
+		t.Fatal(err)
+	}
+
+	// an hour-long stall fires the overdue run once instead of replaying 3600 of them
+	clock.Advance(time.Hour)
+	ttm.CleanExpiredEvents()
+	if fired != 1 {
+		t.Fatalf("fired %d after stall, want 1", fired)
+	}
+	pending := ttm.Pending()
+	if len(pending) != 1 || !pending[0].TriggerTime.Equal(start.Add(time.Hour+time.Second)) {
+		t.Fatalf("unexpected pending %+v", pending)
+	}
+
+	clock.Advance(time.Second)
+	ttm.CleanExpiredEvents()
+	if fired != 2 {
+		t.Fatalf("fired %d after next tick, want 2", fired)
+	}
+}
+
+func TestRecurringInKeyLocation(t *testing.T) {
+	chicago := loadLocation(t, "America/Chicago")
+
+	// Friday before US daylight saving time ends on Sunday 2025-11-02
+	start := time.Date(2025, 10, 31, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(false, clock)
+	ttm.SetLocation("es", chicago)
+
+	settle, err := CronSchedule("0 0 15 * * MON-FRI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fired []Event
+	if _, err = ttm.Subscribe("es", EventForceClose, func(e Event) { fired = append(fired, e) }); err != nil {
+		t.Fatal(err)
+	}
+	if err = ttm.AddRecurring("es", EventForceClose, settle, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, now := range []time.Time{
+		time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 11, 4, 0, 0, 0, 0, time.UTC),
+	} {
+		clock.Set(now)
+		ttm.CleanExpiredEvents()
+	}
+
+	want := []time.Time{
+		time.Date(2025, 10, 31, 20, 0, 0, 0, time.UTC), // 15:00 CDT
+		time.Date(2025, 11, 3, 21, 0, 0, 0, time.UTC),  // 15:00 CST
+	}
+	if len(fired) != len(want) {
+		t.Fatalf("fired %d events, want %d: %+v", len(fired), len(want), fired)
+	}
+	for i, e := range fired {
+		if !e.EventTime.Equal(want[i]) || e.EventTime.Hour() != 15 || e.EventTime.Location() != chicago {
+			t.Fatalf("event %d at %s, want %s", i, e.EventTime, want[i])
+		}
+	}
+}
+
+func TestStopKeepsRecurringEvents(t *testing.T) {
+	start := time.Date(2025, 9, 29, 9, 0, 0, 0, time.Local)
+	clock := NewManualClock(start)
+	ttm := NewTimeEventWithClock(true, clock)
+	ttm.Start()
+
+	var mu sync.Mutex
+	var fired []string
+	collect := func(name string) func(int) {
+		return func(int) {
+			mu.Lock()
+			fired = append(fired, name)
+			mu.Unlock()
+		}
+	}
+	if err := ttm.AddEvent("open", EventOpenAllow, start.Add(time.Hour), 0, collect("open")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ttm.AddEvent("close", EventForceClose, start.Add(6*time.Hour), 0, collect("close")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ttm.AddRecurring("heartbeat", EventOpenAllow, EverySchedule(time.Minute), 0, collect("heartbeat")); err != nil {
+		t.Fatal(err)
+	}
+	ttm.Stop()
+
+	// one-shot events are drained and fired in trigger order, the recurring one stays queued
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fired) != 2 || fired[0] != "open" || fired[1] != "close" {
+		t.Fatalf("fired %v on stop, want open and close", fired)
+	}
+	if pending := ttm.Pending(); len(pending) != 1 || pending[0].Key != "heartbeat" {
+		t.Fatalf("pending after stop %+v, want heartbeat kept", pending)
+	}
+}
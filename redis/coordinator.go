@@ -0,0 +1,16 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/crazy-choose/go/policy"
+)
+
+// NewCoordinator 使用 opt 对应的连接创建事件协调器，prefix 用于区分不同业务的锁
+func NewCoordinator(opt string, prefix string) (*policy.RedisCoordinator, error) {
+	_rc_ := Impl(opt)
+	if _rc_ == nil {
+		return nil, fmt.Errorf("redis(%s) not initialized", opt)
+	}
+	return policy.NewRedisCoordinator(_rc_, prefix), nil
+}
@@ -0,0 +1,16 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/crazy-choose/go/policy"
+)
+
+// NewLimiter 使用 opt 对应的连接创建分布式限流器，同一 Key 的各进程共享限制
+func NewLimiter(opt string, limiter policy.RedisLimiterOption) (*policy.RedisLimiter, error) {
+	_rc_ := Impl(opt)
+	if _rc_ == nil {
+		return nil, fmt.Errorf("redis(%s) not initialized", opt)
+	}
+	return policy.NewRedisLimiter(_rc_, limiter)
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 	"github.com/crazy-choose/helper/log"
 	"github.com/gorilla/websocket"
+	"math/rand"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,6 +25,7 @@ const (
 	TimerIntervalSecond     = 300 * time.Second
 	ReconnectIntervalSecond = 5 * time.Second
 	HeartbeatIntervalSecond = 60 * time.Second
+	ControlWaitSecond       = 1 * time.Second
 )
 
 type ConnectedHandler func(c *Connect)
@@ -28,22 +33,32 @@ type Heartbeat func(c *Connect)
 type TextMessageHandler func(c *Connect, text string) (interface{}, error)
 type BinaryMessageHandler func(c *Connect, binary []byte) (interface{}, error)
 type PingMessageHandler func(c *Connect)
+type PongMessageHandler func(c *Connect, rtt time.Duration)
+type CloseMessageHandler func(c *Connect, code int, text string)
 type ResponseHandler func(c *Connect, response interface{})
 
 type Connect struct {
-	path                 string
+	endpoints            []string
+	active               atomic.Int32
 	header               http.Header
 	conn                 *websocket.Conn
+	connMutex            *sync.RWMutex
 	connectedHandler     ConnectedHandler
 	heartbeat            Heartbeat
 	heartbeatInterval    time.Duration
 	textMessageHandler   TextMessageHandler
 	binaryMessageHandler BinaryMessageHandler
 	pingMessageHandler   PingMessageHandler
+	pongMessageHandler   PongMessageHandler
+	closeMessageHandler  CloseMessageHandler
 	responseHandler      ResponseHandler
+	dispatcher           *dispatcher
+	recorder             atomic.Pointer[Recorder]
 	stopTickerCh         chan bool
-	reconnectCh          chan bool
+	reconnectCh          chan bool // true when the connection went stale, false on a plain connection loss
+	closeCh              chan struct{}
 	lastReceivedTime     time.Time
+	rtt                  atomic.Int64
 	sendMutex            *sync.Mutex
 	option               Option
 	status               string
@@ -52,14 +67,44 @@ type Connect struct {
 type Option struct {
 	Name                string // only show log
 	ReconnectWaitSecond time.Duration
+	TLS                 *TLSOption
+	Proxy               string // http://, https:// or socks5:// proxy, empty uses environment
+	Subprotocols        []string
+	HandshakeTimeout    time.Duration
+	ReadLimit           int64 // max message size in bytes, 0 means no limit
+	ReadBufferSize      int
+	WriteBufferSize     int
+	PingInterval        time.Duration   // interval of client side ping frames, 0 disables
+	PongTimeout         time.Duration   // connection is dead without any frame for this long, defaults to 3*PingInterval
+	RandomEndpoints     bool            // shuffle endpoints once, the first one after shuffling becomes the primary
+	PrimaryRetryTime    time.Duration   // interval to fall back to the primary endpoint while on a backup, 0 disables
+	Dispatch            *DispatchOption // run handlers on a worker pool instead of the read loop, nil keeps them inline
 }
 
 func NewClient(path string, header http.Header, option Option) *Connect {
-	return &Connect{
-		path:                 path,
+	return NewClientWithEndpoints([]string{path}, header, option)
+}
+
+// NewClientWithEndpoints builds a client for one logical feed served by several gateways.
+// endpoints[0] is the sticky primary, the others are tried in order on connect error or staleness.
+func NewClientWithEndpoints(endpoints []string, header http.Header, option Option) *Connect {
+	endpoints = append([]string(nil), endpoints...)
+	if len(endpoints) == 0 {
+		endpoints = []string{""}
+	}
+	if option.RandomEndpoints {
+		rand.Shuffle(len(endpoints), func(i, j int) {
+			endpoints[i], endpoints[j] = endpoints[j], endpoints[i]
+		})
+	}
+
+	c := &Connect{
+		endpoints:            endpoints,
 		header:               header,
 		stopTickerCh:         make(chan bool),
 		reconnectCh:          make(chan bool),
+		closeCh:              make(chan struct{}),
+		connMutex:            &sync.RWMutex{},
 		sendMutex:            &sync.Mutex{},
 		connectedHandler:     func(c *Connect) {},
 		heartbeat:            func(c *Connect) {},
@@ -67,14 +112,22 @@ func NewClient(path string, header http.Header, option Option) *Connect {
 		textMessageHandler:   func(c *Connect, text string) (interface{}, error) { return nil, nil },
 		binaryMessageHandler: func(c *Connect, binary []byte) (interface{}, error) { return nil, nil },
 		pingMessageHandler:   func(c *Connect) {},
+		pongMessageHandler:   func(c *Connect, rtt time.Duration) {},
+		closeMessageHandler:  func(c *Connect, code int, text string) {},
 		responseHandler:      func(c *Connect, response interface{}) {},
 		option:               option,
 		status:               ConnectionNew,
 	}
+
+	if option.Dispatch != nil {
+		c.dispatcher = newDispatcher(option.Dispatch)
+	}
+	return c
 }
 
 func (c *Connect) ConnectAwaitSuccess() {
 	c.connectInfiniteRetry()
+	c.startDispatcher()
 	go c.tickerLoop()
 }
 
@@ -82,14 +135,27 @@ func (c *Connect) Connect() error {
 	if err := c.connect(); err != nil {
 		return err
 	}
+	c.startDispatcher()
 	go c.tickerLoop()
 	return nil
 }
 
+// startDispatcher starts the dispatch workers once connected, they stop on Close.
+func (c *Connect) startDispatcher() {
+	if c.dispatcher != nil {
+		c.dispatcher.start(c)
+	}
+}
+
 func (c *Connect) Status() string {
 	return c.status
 }
 
+// Endpoint returns the endpoint currently in use.
+func (c *Connect) Endpoint() string {
+	return c.endpoints[c.active.Load()]
+}
+
 func (c *Connect) Heartbeat(fn func(c *Connect), interval time.Duration) {
 	c.heartbeat = fn
 	c.heartbeatInterval = interval
@@ -119,24 +185,51 @@ func (c *Connect) SetPingHandler(pingHandler PingMessageHandler) {
 	}
 }
 
+func (c *Connect) SetPongHandler(pongHandler PongMessageHandler) {
+	if pongHandler != nil {
+		c.pongMessageHandler = pongHandler
+	}
+}
+
+func (c *Connect) SetCloseHandler(closeHandler CloseMessageHandler) {
+	if closeHandler != nil {
+		c.closeMessageHandler = closeHandler
+	}
+}
+
+// DispatchStats returns the counters of the handler worker pool, zero when dispatching inline.
+func (c *Connect) DispatchStats() DispatchStats {
+	if c.dispatcher == nil {
+		return DispatchStats{}
+	}
+	return c.dispatcher.stats()
+}
+
+// RTT returns the round trip time measured by the latest client ping.
+func (c *Connect) RTT() time.Duration {
+	return time.Duration(c.rtt.Load())
+}
+
 func (c *Connect) SendString(data []byte) error {
-	if c.conn == nil {
+	conn := c.currentConn()
+	if conn == nil {
 		return errors.New("no connection available")
 	}
 
 	c.sendMutex.Lock()
-	err := c.conn.WriteMessage(websocket.TextMessage, data)
+	err := conn.WriteMessage(websocket.TextMessage, data)
 	c.sendMutex.Unlock()
 	return err
 }
 
 func (c *Connect) SendBinary(data []byte) error {
-	if c.conn == nil {
+	conn := c.currentConn()
+	if conn == nil {
 		return errors.New("no connection available")
 	}
 
 	c.sendMutex.Lock()
-	err := c.conn.WriteMessage(websocket.BinaryMessage, data)
+	err := conn.WriteMessage(websocket.BinaryMessage, data)
 	c.sendMutex.Unlock()
 	return err
 }
@@ -158,6 +251,7 @@ func (c *Connect) SendJsonString(v interface{}) error {
 }
 
 func (c *Connect) Close() {
+	close(c.closeCh)
 	c.stopTicker()
 	c.disconnect()
 	c.status = ConnectionClosed
@@ -169,41 +263,112 @@ func (c *Connect) connectInfiniteRetry() {
 		err error
 	)
 	for {
-		err = c.connect()
+		err = c.connectOnce()
 		if err == nil {
 			break
 		}
-		time.Sleep(ReconnectIntervalSecond)
+		if c.failover() == 0 {
+			time.Sleep(ReconnectIntervalSecond)
+		}
 		rc++
 		c.status = ConnectionTryAgain
 		log.Debug("[WebSocket Client %s] connect retry %d", c.option.Name, rc)
 	}
 }
 
+// connect tries every endpoint once starting from the active one.
 func (c *Connect) connect() error {
 	var err error
-	c.conn, _, err = websocket.DefaultDialer.Dial(c.path, c.header)
+	for range c.endpoints {
+		if err = c.connectOnce(); err == nil {
+			return nil
+		}
+		c.failover()
+	}
+	return err
+}
+
+// failover switches to the next endpoint and returns its index.
+func (c *Connect) failover() int {
+	next := (int(c.active.Load()) + 1) % len(c.endpoints)
+	c.active.Store(int32(next))
+	if len(c.endpoints) > 1 {
+		log.Info("[WebSocket Client %s] failover to endpoint %s", c.option.Name, c.endpoints[next])
+	}
+	return next
+}
+
+func (c *Connect) dial(endpoint string) (*websocket.Conn, error) {
+	dialer, err := c.option.dialer()
+	if err != nil {
+		log.Error("[WebSocket Client %s] dialer config err:%s", c.option.Name, err.Error())
+		return nil, err
+	}
+
+	conn, _, err := dialer.Dial(endpoint, c.header)
+	if err != nil {
+		log.Debug("[WebSocket Client %s] connect %s failed err:%s", c.option.Name, endpoint, err.Error())
+		return nil, err
+	}
+	if c.option.ReadLimit > 0 {
+		conn.SetReadLimit(c.option.ReadLimit)
+	}
+	return conn, nil
+}
+
+func (c *Connect) connectOnce() error {
+	conn, err := c.dial(c.Endpoint())
 	if err != nil {
-		log.Debug("[WebSocket Client %s] connect failed err:%s", c.option.Name, err.Error())
 		return err
 	}
-	log.Info("[WebSocket Client %s] connect success", c.option.Name)
+	c.attach(conn)
+	return nil
+}
+
+func (c *Connect) attach(conn *websocket.Conn) {
+	log.Info("[WebSocket Client %s] connect %s success", c.option.Name, c.Endpoint())
 	c.lastReceivedTime = time.Now()
+	c.setupControlHandlers(conn)
+
+	c.connMutex.Lock()
+	c.conn = conn
+	c.connMutex.Unlock()
 
-	go c.readLoop()
+	go c.readLoop(conn)
 
 	c.connectedHandler(c)
 
 	c.status = ConnectionOpened
-	return nil
+}
+
+// fallback moves back to the primary endpoint once it accepts connections again.
+func (c *Connect) fallback() {
+	if c.active.Load() == 0 {
+		return
+	}
+
+	conn, err := c.dial(c.endpoints[0])
+	if err != nil {
+		return
+	}
+
+	log.Info("[WebSocket Client %s] primary endpoint is back, fallback to %s", c.option.Name, c.endpoints[0])
+	c.disconnect()
+	c.active.Store(0)
+	c.attach(conn)
 }
 
 func (c *Connect) disconnect() {
-	if c.conn == nil {
+	c.connMutex.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.connMutex.Unlock()
+
+	if conn == nil {
 		return
 	}
 
-	err := c.conn.Close()
+	err := conn.Close()
 	if err != nil {
 		log.Error("[WebSocket Client %s] disconnect error: %s", c.option.Name, err)
 		return
@@ -222,12 +387,21 @@ func (c *Connect) tickerLoop() {
 	defer ticker.Stop()
 
 	hbtk := time.NewTicker(c.heartbeatInterval)
-	defer ticker.Stop()
+	defer hbtk.Stop()
+
+	var fallbackCh <-chan time.Time
+	if len(c.endpoints) > 1 && c.option.PrimaryRetryTime > 0 {
+		fbtk := time.NewTicker(c.option.PrimaryRetryTime)
+		defer fbtk.Stop()
+		fallbackCh = fbtk.C
+	}
 
 	for {
 		select {
 		case <-hbtk.C:
 			c.heartbeat(c)
+		case <-fallbackCh:
+			c.fallback()
 		// Receive tick from tickChannel
 		case <-ticker.C:
 			if c.option.ReconnectWaitSecond.Seconds() > 0 {
@@ -237,12 +411,17 @@ func (c *Connect) tickerLoop() {
 				if elapsedSecond > c.option.ReconnectWaitSecond.Seconds() {
 					log.Debug("[WebSocket Client %s] longtime no receive data, do reconnect...", c.option.Name)
 					c.disconnect()
+					c.failover()
 					c.connectInfiniteRetry()
 				}
 			}
-		case <-c.reconnectCh:
+		case stale := <-c.reconnectCh:
 			log.Debug("[WebSocket Client %s] connection loss, do reconnect...", c.option.Name)
 			c.disconnect()
+			// a stale endpoint is abandoned, a dropped connection retries the active endpoint first
+			if stale {
+				c.failover()
+			}
 			time.Sleep(c.option.ReconnectWaitSecond)
 			c.connectInfiniteRetry()
 		case <-c.stopTickerCh:
@@ -252,41 +431,137 @@ func (c *Connect) tickerLoop() {
 	}
 }
 
-func (c *Connect) readLoop() {
-	log.Info("[WebSocket Client %s] read loop started", c.option.Name)
+func (c *Connect) currentConn() *websocket.Conn {
+	c.connMutex.RLock()
+	defer c.connMutex.RUnlock()
+	return c.conn
+}
+
+func (c *Connect) pongTimeout() time.Duration {
+	if c.option.PongTimeout > 0 {
+		return c.option.PongTimeout
+	}
+	return 3 * c.option.PingInterval
+}
+
+// extendReadDeadline marks the connection alive, it is declared dead once no frame arrives before the deadline.
+func (c *Connect) extendReadDeadline(conn *websocket.Conn) {
+	c.lastReceivedTime = time.Now()
+	if timeout := c.pongTimeout(); timeout > 0 {
+		_ = conn.SetReadDeadline(c.lastReceivedTime.Add(timeout))
+	}
+}
+
+func (c *Connect) setupControlHandlers(conn *websocket.Conn) {
+	c.extendReadDeadline(conn)
+
+	conn.SetPingHandler(func(appData string) error {
+		c.extendReadDeadline(conn)
+		c.pingMessageHandler(c)
+		err := conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(ControlWaitSecond))
+		if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+			return err
+		}
+		return nil
+	})
+
+	conn.SetPongHandler(func(appData string) error {
+		c.extendReadDeadline(conn)
+		var rtt time.Duration
+		if sent, err := strconv.ParseInt(appData, 10, 64); err == nil {
+			rtt = time.Since(time.Unix(0, sent))
+			c.rtt.Store(int64(rtt))
+		}
+		c.pongMessageHandler(c, rtt)
+		return nil
+	})
+
+	conn.SetCloseHandler(func(code int, text string) error {
+		log.Info("[WebSocket Client %s] close frame received code:%d text:%s", c.option.Name, code, text)
+		c.closeMessageHandler(c, code, text)
+		message := websocket.FormatCloseMessage(code, "")
+		_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(ControlWaitSecond))
+		return nil
+	})
+}
+
+func (c *Connect) pingLoop(conn *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(c.option.PingInterval)
+	defer ticker.Stop()
+
 	for {
-		if c.conn == nil {
-			log.Error("[WebSocket Client %s] read error: no connection object is null", c.option.Name)
-			time.Sleep(time.Second)
-			continue
+		select {
+		case <-ticker.C:
+			payload := strconv.FormatInt(time.Now().UnixNano(), 10)
+			if err := conn.WriteControl(websocket.PingMessage, []byte(payload), time.Now().Add(ControlWaitSecond)); err != nil {
+				log.Debug("[WebSocket Client %s] ping error: %s", c.option.Name, err)
+				return
+			}
+		case <-stop:
+			return
 		}
+	}
+}
 
-		msgType, buf, err := c.conn.ReadMessage()
+func (c *Connect) readLoop(conn *websocket.Conn) {
+	log.Info("[WebSocket Client %s] read loop started", c.option.Name)
+	defer log.Info("[WebSocket Client %s] read loop stopped", c.option.Name)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if c.option.PingInterval > 0 {
+		go c.pingLoop(conn, stop)
+	}
+
+	for {
+		msgType, buf, err := conn.ReadMessage()
 		if err != nil {
-			log.Error("[WebSocket Client %s] read error: %s", c.option.Name, err)
-			c.reconnectCh <- true
-			break
-		}
+			if c.currentConn() != conn {
+				// disconnected on purpose, the new connection owns the reconnect
+				return
+			}
 
-		c.lastReceivedTime = time.Now()
+			var ne net.Error
+			stale := errors.As(err, &ne) && ne.Timeout()
+			if stale {
+				log.Error("[WebSocket Client %s] connection dead: no frame within %s", c.option.Name, c.pongTimeout())
+			} else {
+				log.Error("[WebSocket Client %s] read error: %s", c.option.Name, err)
+			}
 
-		var result interface{}
-		if msgType == websocket.BinaryMessage {
-			result, err = c.binaryMessageHandler(c, buf)
-		} else if msgType == websocket.TextMessage {
-			result, err = c.textMessageHandler(c, string(buf))
-		} else if msgType == websocket.PingMessage {
-			c.pingMessageHandler(c)
-			continue
+			select {
+			case c.reconnectCh <- stale:
+			case <-c.closeCh:
+			}
+			return
 		}
 
-		if err != nil {
-			log.Error("[WebSocket Client %s] handle message error: %s", c.option.Name, err)
+		c.extendReadDeadline(conn)
+		c.record(msgType, buf)
+
+		if c.dispatcher != nil {
+			c.dispatcher.dispatch(c, frame{t: msgType, buf: buf})
 			continue
 		}
+		c.handleMessage(msgType, buf)
+	}
+}
 
-		c.responseHandler(c, result)
+func (c *Connect) handleMessage(msgType int, buf []byte) {
+	var (
+		result interface{}
+		err    error
+	)
+	if msgType == websocket.BinaryMessage {
+		result, err = c.binaryMessageHandler(c, buf)
+	} else if msgType == websocket.TextMessage {
+		result, err = c.textMessageHandler(c, string(buf))
+	}
+
+	if err != nil {
+		log.Error("[WebSocket Client %s] handle message error: %s", c.option.Name, err)
+		return
 	}
 
-	log.Info("[WebSocket Client %s] read loop stopped", c.option.Name)
+	c.responseHandler(c, result)
 }
@@ -1,7 +1,14 @@
 package ws
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 func TestNewClient(t *testing.T) {
@@ -33,3 +40,212 @@ func newClient(t *testing.T) {
 	client.ConnectAwaitSuccess()
 	t.Log("complete")
 }
+
+func newTestServer(t *testing.T, handle func(conn *websocket.Conn)) (*httptest.Server, *atomic.Int32) {
+	var accepted atomic.Int32
+	up := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		accepted.Add(1)
+		handle(conn)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &accepted
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestClientPingMeasuresRTT(t *testing.T) {
+	srv, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client := NewClient(wsURL(srv), nil, Option{Name: "rtt", PingInterval: 20 * time.Millisecond})
+	pong := make(chan time.Duration, 1)
+	client.SetPongHandler(func(c *Connect, rtt time.Duration) {
+		select {
+		case pong <- rtt:
+		default:
+		}
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	select {
+	case rtt := <-pong:
+		if rtt <= 0 || client.RTT() <= 0 {
+			t.Fatalf("rtt not measured: %s %s", rtt, client.RTT())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no pong received")
+	}
+}
+
+func TestClientAnswersServerPing(t *testing.T) {
+	pong := make(chan string, 1)
+	srv, _ := newTestServer(t, func(conn *websocket.Conn) {
+		conn.SetPongHandler(func(appData string) error {
+			pong <- appData
+			return nil
+		})
+		_ = conn.WriteControl(websocket.PingMessage, []byte("srv"), time.Now().Add(time.Second))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client := NewClient(wsURL(srv), nil, Option{Name: "server-ping"})
+	pinged := make(chan struct{}, 1)
+	client.SetPingHandler(func(c *Connect) { pinged <- struct{}{} })
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	select {
+	case <-pinged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ping handler not called")
+	}
+	select {
+	case data := <-pong:
+		if data != "srv" {
+			t.Fatalf("pong payload = %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("pong not sent")
+	}
+}
+
+func TestClientReconnectsOnPongTimeout(t *testing.T) {
+	srv, accepted := newTestServer(t, func(conn *websocket.Conn) {
+		// swallow pings so the client never sees a pong
+		conn.SetPingHandler(func(string) error { return nil })
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client := NewClient(wsURL(srv), nil, Option{
+		Name:         "dead",
+		PingInterval: 20 * time.Millisecond,
+		PongTimeout:  100 * time.Millisecond,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for accepted.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("dead connection was not replaced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientEndpointFailover(t *testing.T) {
+	var primaryUp atomic.Bool
+	up := websocket.Upgrader{}
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !primaryUp.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err = conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer primary.Close()
+	backup, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client := NewClientWithEndpoints([]string{wsURL(primary), wsURL(backup)}, nil, Option{
+		Name:             "failover",
+		PrimaryRetryTime: 20 * time.Millisecond,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if client.Endpoint() != wsURL(backup) {
+		t.Fatalf("endpoint = %s, want backup", client.Endpoint())
+	}
+
+	primaryUp.Store(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for client.Endpoint() != wsURL(primary) {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not fall back to primary")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientStaysOnPrimaryAfterConnectionLoss(t *testing.T) {
+	var drops atomic.Int32
+	primary, accepted := newTestServer(t, func(conn *websocket.Conn) {
+		if drops.Add(1) == 1 {
+			return // drop the first connection right away
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	backup, backupAccepted := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client := NewClientWithEndpoints([]string{wsURL(primary), wsURL(backup)}, nil, Option{Name: "sticky"})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for accepted.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not reconnect to the primary")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.Endpoint() != wsURL(primary) || backupAccepted.Load() != 0 {
+		t.Fatalf("endpoint = %s after a brief drop, backup connections %d", client.Endpoint(), backupAccepted.Load())
+	}
+}
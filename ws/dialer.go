@@ -0,0 +1,88 @@
+package ws
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/gorilla/websocket"
+)
+
+var ErrInvalidCA = errors.New("websocket client no valid certificate found in ca file")
+
+// TLSOption configures the TLS handshake of the client dialer.
+type TLSOption struct {
+	CAFile             string         // PEM encoded CA bundle
+	RootCAs            *x509.CertPool // takes precedence over CAFile
+	CertFile           string         // PEM client certificate for mutual TLS
+	KeyFile            string         // PEM client private key
+	ServerName         string         // overrides SNI and the verified host name
+	InsecureSkipVerify bool           // test environments only
+}
+
+func (o Option) dialer() (*websocket.Dialer, error) {
+	d := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+		ReadBufferSize:   o.ReadBufferSize,
+		WriteBufferSize:  o.WriteBufferSize,
+		Subprotocols:     o.Subprotocols,
+	}
+
+	if o.HandshakeTimeout > 0 {
+		d.HandshakeTimeout = o.HandshakeTimeout
+	}
+
+	if o.Proxy != "" {
+		u, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("websocket client invalid proxy %q: %w", o.Proxy, err)
+		}
+		d.Proxy = http.ProxyURL(u)
+	}
+
+	if o.TLS != nil {
+		cfg, err := o.TLS.config()
+		if err != nil {
+			return nil, err
+		}
+		d.TLSClientConfig = cfg
+	}
+
+	return d, nil
+}
+
+func (o *TLSOption) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.RootCAs != nil {
+		cfg.RootCAs = o.RootCAs
+	} else if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("websocket client read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidCA
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("websocket client load key pair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
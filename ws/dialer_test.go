@@ -0,0 +1,251 @@
+package ws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	srv := newUnstartedTLSServer()
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newUnstartedTLSServer() *httptest.Server {
+	up := websocket.Upgrader{Subprotocols: []string{"feed.v2"}}
+	return httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, buf, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err = conn.WriteMessage(mt, buf); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func wssURL(srv *httptest.Server) string {
+	return "wss" + strings.TrimPrefix(srv.URL, "https")
+}
+
+func writeCAFile(t *testing.T, srv *httptest.Server) string {
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestConnectTLSCustomCA(t *testing.T) {
+	srv := newTLSServer(t)
+
+	client := NewClient(wssURL(srv), nil, Option{
+		Name:             "tls-ca",
+		TLS:              &TLSOption{CAFile: writeCAFile(t, srv)},
+		Subprotocols:     []string{"feed.v2"},
+		HandshakeTimeout: 2 * time.Second,
+		ReadLimit:        1024,
+	})
+
+	received := make(chan string, 1)
+	client.SetHandler(nil, func(c *Connect, text string) (interface{}, error) {
+		received <- text
+		return nil, nil
+	}, nil, nil)
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Close()
+
+	if got := client.conn.Subprotocol(); got != "feed.v2" {
+		t.Fatalf("subprotocol = %q, want feed.v2", got)
+	}
+
+	if err := client.SendString([]byte("hello")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	select {
+	case text := <-received:
+		if text != "hello" {
+			t.Fatalf("echo = %q", text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no echo received")
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and key, returning the files and the certificate
+func writeClientCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ws-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestConnectMutualTLS(t *testing.T) {
+	certFile, keyFile, cert := writeClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+
+	srv := newUnstartedTLSServer()
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	ca := writeCAFile(t, srv)
+
+	client := NewClient(wssURL(srv), nil, Option{
+		Name:             "tls-mutual",
+		TLS:              &TLSOption{CAFile: ca, CertFile: certFile, KeyFile: keyFile},
+		HandshakeTimeout: 2 * time.Second,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("connect with client certificate: %v", err)
+	}
+	client.Close()
+
+	anonymous := NewClient(wssURL(srv), nil, Option{
+		Name:             "tls-anonymous",
+		TLS:              &TLSOption{CAFile: ca},
+		HandshakeTimeout: 2 * time.Second,
+	})
+	if err := anonymous.Connect(); err == nil {
+		anonymous.Close()
+		t.Fatal("expected handshake failure without client certificate")
+	}
+
+	if _, err := (Option{TLS: &TLSOption{CertFile: certFile}}).dialer(); err == nil {
+		t.Fatal("expected error for missing key file")
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	srv := newTLSServer(t)
+	client := NewClient(wssURL(srv), nil, Option{
+		Name:      "read-limit",
+		TLS:       &TLSOption{CAFile: writeCAFile(t, srv)},
+		ReadLimit: 1024,
+	})
+
+	conn, err := client.dial(client.Endpoint())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err = conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 512))); err != nil {
+		t.Fatal(err)
+	}
+	if _, buf, err := conn.ReadMessage(); err != nil || len(buf) != 512 {
+		t.Fatalf("read within limit: %d bytes, %v", len(buf), err)
+	}
+
+	if err = conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 2048))); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = conn.ReadMessage(); !errors.Is(err, websocket.ErrReadLimit) {
+		t.Fatalf("err = %v, want ErrReadLimit", err)
+	}
+}
+
+func TestConnectTLSUnknownCA(t *testing.T) {
+	srv := newTLSServer(t)
+
+	client := NewClient(wssURL(srv), nil, Option{Name: "tls-unknown", HandshakeTimeout: 2 * time.Second})
+	if err := client.Connect(); err == nil {
+		client.Close()
+		t.Fatal("expected certificate verification error")
+	}
+}
+
+func TestConnectTLSInsecureSkipVerify(t *testing.T) {
+	srv := newTLSServer(t)
+
+	client := NewClient(wssURL(srv), nil, Option{
+		Name: "tls-insecure",
+		TLS:  &TLSOption{InsecureSkipVerify: true},
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	client.Close()
+}
+
+func TestDialerConfig(t *testing.T) {
+	_, err := Option{TLS: &TLSOption{CAFile: filepath.Join(t.TempDir(), "missing.pem")}}.dialer()
+	if err == nil {
+		t.Fatal("expected error for missing ca file")
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.pem")
+	if err = os.WriteFile(bad, []byte("not a cert"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = (Option{TLS: &TLSOption{CAFile: bad}}).dialer(); err != ErrInvalidCA {
+		t.Fatalf("err = %v, want ErrInvalidCA", err)
+	}
+
+	d, err := Option{Proxy: "socks5://127.0.0.1:1080", ReadBufferSize: 4096}.dialer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	u, _ := d.Proxy(req)
+	if u == nil || u.Scheme != "socks5" || d.ReadBufferSize != 4096 {
+		t.Fatalf("unexpected dialer: proxy=%v buffer=%d", u, d.ReadBufferSize)
+	}
+}
@@ -0,0 +1,166 @@
+package ws
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/crazy-choose/helper/log"
+)
+
+// KeyFunc extracts the partition key of a received frame, e.g. the instrument id.
+// Frames sharing a key are handled in arrival order, different keys run in parallel.
+type KeyFunc func(msgType int, payload []byte) string
+
+type DispatchOption struct {
+	Workers   int     // number of handler goroutines, defaults to 1
+	QueueSize int     // buffered frames per worker, defaults to DispatchQueueSize
+	Key       KeyFunc // nil sends every frame to the first worker
+	Block     bool    // block the read loop when a queue is full instead of dropping the frame
+}
+
+type DispatchStats struct {
+	Dispatched uint64
+	Processed  uint64
+	Dropped    uint64
+	Pending    int
+}
+
+const DispatchQueueSize = 256
+
+type frame struct {
+	t   int
+	buf []byte
+}
+
+type dispatcher struct {
+	key        KeyFunc
+	block      bool
+	queues     []chan frame
+	dispatched atomic.Uint64
+	processed  atomic.Uint64
+	dropped    atomic.Uint64
+
+	mu   sync.Mutex // guards refs and stop
+	refs int
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newDispatcher(opt *DispatchOption) *dispatcher {
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	size := opt.QueueSize
+	if size <= 0 {
+		size = DispatchQueueSize
+	}
+
+	d := &dispatcher{
+		key:    opt.Key,
+		block:  opt.Block,
+		queues: make([]chan frame, workers),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan frame, size)
+	}
+	return d
+}
+
+// start runs the workers until the returned release is called by every starter
+// or the client is closed. Connect never releases; Replay releases once it has
+// dispatched the whole file, so an offline client does not keep goroutines.
+func (d *dispatcher) start(c *Connect) (release func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.refs++
+	if d.refs == 1 {
+		d.stop = make(chan struct{})
+		for _, q := range d.queues {
+			d.wg.Add(1)
+			go d.work(c, q, d.stop)
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.refs--; d.refs == 0 {
+				close(d.stop)
+				d.wg.Wait()
+			}
+		})
+	}
+}
+
+func (d *dispatcher) work(c *Connect, q chan frame, stop chan struct{}) {
+	defer d.wg.Done()
+	for {
+		select {
+		case f := <-q:
+			d.handle(c, f)
+		case <-stop:
+			// handle what is already queued before returning
+			for {
+				select {
+				case f := <-q:
+					d.handle(c, f)
+				default:
+					return
+				}
+			}
+		case <-c.closeCh:
+			return
+		}
+	}
+}
+
+func (d *dispatcher) handle(c *Connect, f frame) {
+	c.handleMessage(f.t, f.buf)
+	d.processed.Add(1)
+}
+
+func (d *dispatcher) queue(f frame) chan frame {
+	if d.key == nil || len(d.queues) == 1 {
+		return d.queues[0]
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(d.key(f.t, f.buf)))
+	return d.queues[h.Sum32()%uint32(len(d.queues))]
+}
+
+func (d *dispatcher) dispatch(c *Connect, f frame) {
+	q := d.queue(f)
+	if d.block {
+		select {
+		case q <- f:
+			d.dispatched.Add(1)
+		case <-c.closeCh:
+		}
+		return
+	}
+
+	select {
+	case q <- f:
+		d.dispatched.Add(1)
+	default:
+		d.dropped.Add(1)
+		log.Error("[WebSocket Client %s] dispatch queue full, frame dropped", c.option.Name)
+	}
+}
+
+func (d *dispatcher) stats() DispatchStats {
+	s := DispatchStats{
+		Dispatched: d.dispatched.Load(),
+		Processed:  d.processed.Load(),
+		Dropped:    d.dropped.Load(),
+	}
+	for _, q := range d.queues {
+		s.Pending += len(q)
+	}
+	return s
+}
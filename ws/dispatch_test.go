@@ -0,0 +1,163 @@
+package ws
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDispatchKeepsPerKeyOrder(t *testing.T) {
+	const perKey = 50
+	keys := []string{"rb2501", "au2502", "cu2503", "ag2504"}
+
+	srv, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for i := 0; i < perKey; i++ {
+			for _, k := range keys {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("%s:%03d", k, i)))
+			}
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string][]string)
+		done = make(chan struct{})
+		n    int
+	)
+	client := NewClient(wsURL(srv), nil, Option{
+		Name: "dispatch",
+		Dispatch: &DispatchOption{
+			Workers: 4,
+			Key: func(msgType int, payload []byte) string {
+				return strings.SplitN(string(payload), ":", 2)[0]
+			},
+		},
+	})
+	client.SetHandler(nil, func(c *Connect, text string) (interface{}, error) {
+		parts := strings.SplitN(text, ":", 2)
+		mu.Lock()
+		seen[parts[0]] = append(seen[parts[0]], parts[1])
+		n++
+		if n == perKey*len(keys) {
+			close(done)
+		}
+		mu.Unlock()
+		return nil, nil
+	}, nil, nil)
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("handled %d frames", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, k := range keys {
+		for i, v := range seen[k] {
+			if v != fmt.Sprintf("%03d", i) {
+				t.Fatalf("key %s out of order at %d: %s", k, i, v)
+			}
+		}
+	}
+	if s := client.DispatchStats(); s.Dispatched != perKey*uint64(len(keys)) || s.Dropped != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestDispatchOverflow(t *testing.T) {
+	srv, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for i := 0; i < 10; i++ {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte("tick"))
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	release := make(chan struct{})
+	client := NewClient(wsURL(srv), nil, Option{
+		Name:     "overflow",
+		Dispatch: &DispatchOption{Workers: 1, QueueSize: 1},
+	})
+	client.SetHandler(nil, func(c *Connect, text string) (interface{}, error) {
+		<-release
+		return nil, nil
+	}, nil, nil)
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.DispatchStats().Dropped == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no frame dropped: %+v", client.DispatchStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDispatchWorkersStopAfterReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "feed.rec")
+	rec, err := NewRecorder(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err = rec.Record(time.Now(), websocket.TextMessage, []byte(fmt.Sprintf("quote-%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	before := runtime.NumGoroutine()
+	client := NewClient("", nil, Option{Name: "replay", Dispatch: &DispatchOption{Workers: 4}})
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines started before Connect or Replay", n-before)
+	}
+
+	var (
+		mu      sync.Mutex
+		handled int
+	)
+	client.SetHandler(nil, func(c *Connect, text string) (interface{}, error) {
+		mu.Lock()
+		handled++
+		mu.Unlock()
+		return nil, nil
+	}, nil, nil)
+	if err = client.Replay(file, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay returns once every frame is handled and its workers are gone
+	mu.Lock()
+	defer mu.Unlock()
+	if handled != 20 {
+		t.Fatalf("handled %d frames, want 20", handled)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d dispatch workers still running after Replay", n-before)
+	}
+}
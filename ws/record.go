@@ -0,0 +1,211 @@
+package ws
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/crazy-choose/helper/log"
+)
+
+// record file layout: recordMagic, then per frame
+// | unix nano int64 | message type uint8 | payload length uint32 | payload |
+// all integers are big endian.
+const recordMagic = "WSREC1\n"
+
+// MaxRecordPayload bounds a recorded frame so a corrupt length header
+// cannot make the reader allocate gigabytes.
+const MaxRecordPayload = 64 << 20
+
+var (
+	ErrBadRecord      = errors.New("websocket record file has unknown format")
+	ErrRecordTooLarge = errors.New("websocket record frame exceeds MaxRecordPayload")
+)
+
+type RecordedFrame struct {
+	Time    time.Time
+	Type    int
+	Payload []byte
+}
+
+// Recorder writes received frames to a file for later Replay.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+}
+
+func NewRecorder(filename string) (*Recorder, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{file: f, w: bufio.NewWriter(f)}
+	if _, err = r.w.WriteString(recordMagic); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) Record(t time.Time, msgType int, payload []byte) error {
+	if len(payload) > MaxRecordPayload {
+		return ErrRecordTooLarge
+	}
+
+	var head [13]byte
+	binary.BigEndian.PutUint64(head[0:8], uint64(t.UnixNano()))
+	head[8] = byte(msgType)
+	binary.BigEndian.PutUint32(head[9:13], uint32(len(payload)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.w.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := r.w.Write(payload)
+	return err
+}
+
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.w.Flush()
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// RecordReader iterates over the frames of a record file.
+type RecordReader struct {
+	file *os.File
+	r    *bufio.Reader
+}
+
+func OpenRecord(filename string) (*RecordReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rr := &RecordReader{file: f, r: bufio.NewReader(f)}
+	magic := make([]byte, len(recordMagic))
+	if _, err = io.ReadFull(rr.r, magic); err != nil || string(magic) != recordMagic {
+		_ = f.Close()
+		return nil, ErrBadRecord
+	}
+	return rr, nil
+}
+
+// Next returns the next frame, io.EOF once the file is exhausted.
+func (rr *RecordReader) Next() (RecordedFrame, error) {
+	var head [13]byte
+	if _, err := io.ReadFull(rr.r, head[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return RecordedFrame{}, ErrBadRecord
+		}
+		return RecordedFrame{}, err
+	}
+
+	// read through a LimitReader so a truncated file only costs what it holds
+	n := int64(binary.BigEndian.Uint32(head[9:13]))
+	if n > MaxRecordPayload {
+		return RecordedFrame{}, ErrBadRecord
+	}
+	payload, err := io.ReadAll(io.LimitReader(rr.r, n))
+	if err != nil || int64(len(payload)) != n {
+		return RecordedFrame{}, ErrBadRecord
+	}
+
+	return RecordedFrame{
+		Time:    time.Unix(0, int64(binary.BigEndian.Uint64(head[0:8]))),
+		Type:    int(head[8]),
+		Payload: payload,
+	}, nil
+}
+
+func (rr *RecordReader) Close() error {
+	return rr.file.Close()
+}
+
+// SetRecorder records every received text and binary frame, nil stops recording.
+func (c *Connect) SetRecorder(r *Recorder) {
+	c.recorder.Store(r)
+}
+
+func (c *Connect) record(msgType int, buf []byte) {
+	r := c.recorder.Load()
+	if r == nil {
+		return
+	}
+	err := r.Record(time.Now(), msgType, buf)
+	if errors.Is(err, ErrRecordTooLarge) {
+		log.Error("[WebSocket Client %s] record skipped frame of %d bytes", c.option.Name, len(buf))
+		return
+	}
+	if err != nil {
+		c.recorder.CompareAndSwap(r, nil)
+		log.Error("[WebSocket Client %s] record error, recording stopped: %s", c.option.Name, err)
+	}
+}
+
+// Replay feeds a recorded session to the handlers without any network.
+// speed 1 keeps the original pacing, 2 plays twice as fast, 0 or less plays without delay.
+// With Option.Dispatch set it returns once every frame has been handled.
+func (c *Connect) Replay(filename string, speed float64) error {
+	rr, err := OpenRecord(filename)
+	if err != nil {
+		return err
+	}
+	defer rr.Close()
+
+	if c.dispatcher != nil {
+		// waits for the replayed frames to be handled, then stops workers that only Replay started
+		release := c.dispatcher.start(c)
+		defer release()
+	}
+
+	var (
+		first time.Time
+		start = time.Now()
+	)
+	for {
+		f, err := rr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("websocket replay %s: %w", filename, err)
+		}
+
+		if speed > 0 {
+			if first.IsZero() {
+				first = f.Time
+			}
+			due := start.Add(time.Duration(float64(f.Time.Sub(first)) / speed))
+			if wait := time.Until(due); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		c.lastReceivedTime = time.Now()
+		if c.dispatcher != nil {
+			c.dispatcher.dispatch(c, frame{t: f.Type, buf: f.Payload})
+			continue
+		}
+		c.handleMessage(f.Type, f.Payload)
+	}
+}
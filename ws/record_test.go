@@ -0,0 +1,144 @@
+package ws
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	srv, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for i := 0; i < 5; i++ {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("quote-%d", i)))
+			time.Sleep(20 * time.Millisecond)
+		}
+		_ = conn.WriteMessage(websocket.BinaryMessage, []byte{0x01, 0x02})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	file := filepath.Join(t.TempDir(), "feed.rec")
+	rec, err := NewRecorder(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := NewClient(wsURL(srv), nil, Option{Name: "record"})
+	done := make(chan struct{})
+	live.SetHandler(nil, nil, func(c *Connect, binary []byte) (interface{}, error) {
+		close(done)
+		return nil, nil
+	}, nil)
+	live.SetRecorder(rec)
+	if err = live.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("live feed not received")
+	}
+	live.Close()
+	if err = rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	offline := NewClient("", nil, Option{Name: "replay"})
+	offline.SetHandler(nil, func(c *Connect, text string) (interface{}, error) {
+		got = append(got, text)
+		return nil, nil
+	}, func(c *Connect, binary []byte) (interface{}, error) {
+		got = append(got, fmt.Sprintf("%x", binary))
+		return nil, nil
+	}, nil)
+
+	start := time.Now()
+	if err = offline.Replay(file, 0); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("unpaced replay took %s", elapsed)
+	}
+
+	want := []string{"quote-0", "quote-1", "quote-2", "quote-3", "quote-4", "0102"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("replayed %v, want %v", got, want)
+	}
+
+	got = nil
+	start = time.Now()
+	if err = offline.Replay(file, 1); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("paced replay took only %s", elapsed)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("replayed %d frames, want %d", len(got), len(want))
+	}
+}
+
+func TestOpenRecordRejectsUnknownFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "empty.rec")
+	rec, err := NewRecorder(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rec.Close()
+
+	if _, err = OpenRecord(filepath.Join(t.TempDir(), "missing.rec")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	rr, err := OpenRecord(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	if _, err = rr.Next(); err == nil {
+		t.Fatal("expected EOF on empty record")
+	}
+}
+
+func TestRecordReaderRejectsBadLength(t *testing.T) {
+	for name, length := range map[string]uint32{
+		"oversized": 0xFFFFFFFF,
+		"truncated": 1024,
+	} {
+		file := filepath.Join(t.TempDir(), name+".rec")
+		var head [13]byte
+		binary.BigEndian.PutUint64(head[0:8], uint64(time.Now().UnixNano()))
+		head[8] = websocket.TextMessage
+		binary.BigEndian.PutUint32(head[9:13], length)
+		data := append([]byte(recordMagic), head[:]...)
+		if err := os.WriteFile(file, append(data, "short"...), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		rr, err := OpenRecord(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = rr.Next(); err != ErrBadRecord {
+			t.Fatalf("%s: err = %v, want ErrBadRecord", name, err)
+		}
+		_ = rr.Close()
+	}
+
+	rec, err := NewRecorder(filepath.Join(t.TempDir(), "large.rec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Close()
+	if err = rec.Record(time.Now(), websocket.BinaryMessage, make([]byte, MaxRecordPayload+1)); err != ErrRecordTooLarge {
+		t.Fatalf("err = %v, want ErrRecordTooLarge", err)
+	}
+}